@@ -1,6 +1,18 @@
 package middlewarex
 
-import "github.com/labstack/echo/v5"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
 
 // All of the methods are the same type as HandlerFunc
 // if you don't want to support any methods of CRUD, then don't implement it
@@ -25,31 +37,710 @@ type UpdateSupported interface {
 	Update(*echo.Context) error
 }
 
+// ReplaceSupported interface
+type ReplaceSupported interface {
+	Replace(*echo.Context) error
+}
+
 // DeleteSupported interface
 type DeleteSupported interface {
 	Delete(*echo.Context) error
 }
 
+// TypedSupported interface
+// It describes the values of a resource (e.g. TypedResource) and is used to generate the OpenAPI schemas.
+type TypedSupported interface {
+	Type() reflect.Type
+	IDType() reflect.Type
+}
+
+// CRUDConfig defines the config for CRUD registration.
+type CRUDConfig struct {
+	// IDParam is the name of the path parameter of the member routes (e.g. "uuid" for `/path/:uuid').
+	// Optional. Default value "id".
+	IDParam string
+
+	// IDValidator validates the ID path parameter before calling Show, Update, Replace and Delete.
+	// Invalid IDs are rejected with "400 - Bad Request" error.
+	// Optional.
+	IDValidator IDValidator
+
+	// UpdateMethods defines the HTTP methods on which Update is exposed (PATCH and/or PUT).
+	// When the resource implements ReplaceSupported, PUT is always used for Replace.
+	// Optional. Default value [PATCH].
+	UpdateMethods []string
+
+	// ParentIDParam is the name of the ID path parameter in the routes of nested resources.
+	// Optional. Default value is the singular form of the path's last segment suffixed by IDParam (e.g. "project_id" for `/projects').
+	ParentIDParam string
+
+	// ListQuery enables the parsing of the pagination, sorting and filtering query parameters of List requests.
+	// The parsed ListQuery is available in the List handler with ListQueryFrom.
+	// Optional.
+	ListQuery *ListQueryConfig
+
+	// MaxBulkSize is the maximum number of items of bulk operations, greater batches are rejected with "413 - Request Entity Too Large" error.
+	// Optional. Default value 100.
+	MaxBulkSize int
+
+	// Name is the prefix of the route names, suffixed by the action (e.g. "tests.list", "tests.show").
+	// Optional. Default value is the path's last segment, prefixed by the parent's name for nested resources (e.g. "projects.tasks").
+	Name string
+
+	// Middlewares defines the middlewares of some actions, they run before the ID validation and the ListQuery parsing.
+	// e.g. []VerbMiddleware{WithMiddleware(VerbCreate|VerbUpdate|VerbDelete, auth)}
+	// Optional.
+	Middlewares []VerbMiddleware
+
+	// CORS enables the CORS headers, the preflight requests allow the methods of the registered verbs and actions.
+	// Optional.
+	CORS *CORSConfig
+
+	// ETag enables the optimistic concurrency control of the members:
+	// Show and List set the ETag header and honour If-None-Match with "304 - Not Modified",
+	// Update, Replace and Delete require a matching If-Match header ("428 - Precondition Required" when missing, "412 - Precondition Failed" on mismatch).
+	// The entity tags come from the resource when it implements ETagSupported, from a hash of the Show response otherwise.
+	// Bulk updates and deletions cannot carry preconditions, CRUD panics if the resource implements BulkUpdateSupported or BulkDeleteSupported.
+	// Optional.
+	ETag bool
+
+	// Fields enables the `?fields=' selection of the fields of the Create, List, Show, Update, Replace and Restore JSON responses
+	// and removes the hidden fields from them.
+	// Optional.
+	Fields *FieldsConfig
+
+	// Policy defines the enforcement of the resources implementing PolicySupported.
+	// Optional. Default value DefaultCRUDPolicyConfig.
+	Policy *CRUDPolicyConfig
+
+	// Singular registers a resource without ID (e.g. `/me', `/settings'):
+	// Show, Create, Update, Replace, Delete and the member actions are registered on the path itself,
+	// List and the bulk operations are not registered and IDValidator is not used.
+	// Nested resources are registered directly under the path (e.g. `/me/tasks/:id').
+	// Typed resources (see CRUDOf) are not supported, CRUD panics.
+	// Optional.
+	Singular bool
+}
+
+// Verb is a set of CRUD actions.
+type Verb uint
+
+// CRUD actions
+const (
+	VerbCreate Verb = 1 << iota
+	VerbList
+	VerbShow
+	VerbUpdate
+	VerbReplace
+	VerbDelete
+	VerbBulkCreate
+	VerbBulkUpdate
+	VerbBulkDelete
+	// VerbAction is the set of the custom actions (see Action).
+	VerbAction
+	VerbRestore
+	VerbPurge
+
+	// VerbRead is the set of read-only actions.
+	VerbRead = VerbList | VerbShow
+	// VerbWrite is the set of actions modifying the resource.
+	VerbWrite = VerbCreate | VerbUpdate | VerbReplace | VerbDelete | VerbBulkCreate | VerbBulkUpdate | VerbBulkDelete | VerbRestore | VerbPurge
+	// VerbAll is the set of all actions.
+	VerbAll = VerbRead | VerbWrite | VerbAction
+)
+
+var verbNames = []string{"create", "list", "show", "update", "replace", "delete", "bulk_create", "bulk_update", "bulk_delete", "action", "restore", "purge"}
+
+// VerbMiddleware defines middlewares applied to a set of CRUD actions.
+type VerbMiddleware struct {
+	Verbs       Verb
+	Actions     []string // Names of custom actions
+	Middlewares []echo.MiddlewareFunc
+}
+
+// WithMiddleware returns a VerbMiddleware applying the given middlewares to the given actions.
+func WithMiddleware(verbs Verb, middlewares ...echo.MiddlewareFunc) VerbMiddleware {
+	return VerbMiddleware{
+		Verbs:       verbs,
+		Middlewares: middlewares,
+	}
+}
+
+// WithActionMiddleware returns a VerbMiddleware applying the given middlewares to the named custom actions.
+func WithActionMiddleware(actions []string, middlewares ...echo.MiddlewareFunc) VerbMiddleware {
+	return VerbMiddleware{
+		Actions:     actions,
+		Middlewares: middlewares,
+	}
+}
+
+// String returns the names of the actions joined by "|" (e.g. "create|update").
+// They are used as suffix of the route names.
+func (v Verb) String() string {
+	var names []string
+	for i, name := range verbNames {
+		if v&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// IDValidator defines a function which validates a resource ID.
+type IDValidator func(id string) error
+
+// Resource is the handle of a resource registered with CRUD.
+// It is used to register nested resources under its member path.
+type Resource struct {
+	group    *echo.Group
+	path     string
+	config   CRUDConfig
+	parents  []resourceParent
+	routes   echo.Routes
+	prefix   string
+	verbs    Verb
+	resource any
+	typed    TypedSupported
+	children []*Resource
+
+	collectionMW []echo.MiddlewareFunc
+	memberMW     []echo.MiddlewareFunc
+	methods      map[string][]string
+}
+
+// ResourceInfo describes a resource registered with CRUD.
+type ResourceInfo struct {
+	// Name is the prefix of the route names.
+	Name string
+	// Path is the full collection path, including the group prefix and the parent IDs (e.g. `/v1/projects/:project_id/tasks').
+	Path string
+	// IDParam is the name of the ID path parameter, empty for singular resources.
+	IDParam string
+	// Verbs is the set of actions of the resource.
+	Verbs Verb
+	// Type is the type of the values of a typed resource, nil otherwise.
+	Type reflect.Type
+	// IDType is the type of the IDs of a typed resource, nil otherwise.
+	IDType reflect.Type
+	// JSONAPIType is the type of the resource objects of a typed resource rendered as JSON:API (see TypedCRUDConfig.JSONAPI), empty otherwise.
+	JSONAPIType string
+	// ListQuery is the ListQuery config of the resource, if any.
+	ListQuery *ListQueryConfig
+	// SoftDelete is true when the resource implements SoftDeleteSupported.
+	SoftDelete bool
+	// Fields is the fields selection config of the resource, if any.
+	Fields *FieldsConfig
+	// Routes are the registered routes.
+	Routes echo.Routes
+}
+
+type resourceParent struct {
+	param     string
+	validator IDValidator
+}
+
+// DefaultCRUDConfig is the default CRUD config.
+var DefaultCRUDConfig = CRUDConfig{
+	IDParam:       "id",
+	UpdateMethods: []string{http.MethodPatch},
+}
+
+const parentIDsContextKey = "middlewarex.crud.parents"
+
 // CRUD defines the folowwing resources:
 //   POST:   /path
 //   GET:    /path
 //   GET:    /path/:id
 //   PATCH:  /path/:id
+//   PUT:    /path/:id (Replace)
 //   DEL:    /path/:id
-func CRUD(group *echo.Group, path string, resource interface{}) {
+//   POST:   /path/_bulk (BulkCreate)
+//   PATCH:  /path/_bulk (BulkUpdate)
+//   DEL:    /path?ids=1,2,3 (BulkDelete)
+//   POST:   /path/:id/name and /path/name (Actions)
+//   DEL:    /path/:id (SoftDelete)
+//   POST:   /path/:id/restore (Restore)
+//   DEL:    /path/:id/purge (Purge)
+func CRUD(group *echo.Group, path string, resource interface{}) *Resource {
+	return CRUDWithConfig(group, path, resource, DefaultCRUDConfig)
+}
+
+// CRUDWithConfig defines the CRUD resources with config.
+func CRUDWithConfig(group *echo.Group, path string, resource interface{}, config CRUDConfig) *Resource {
+	return registerResource(group, path, resource, config, nil, "")
+}
+
+// CRUD defines the CRUD resources of a child resource under the member path of r.
+// e.g. `/projects/:project_id/tasks/:id'
+func (r *Resource) CRUD(path string, resource interface{}) *Resource {
+	return r.CRUDWithConfig(path, resource, DefaultCRUDConfig)
+}
+
+// CRUDWithConfig defines the CRUD resources of a child resource under the member path of r with config.
+func (r *Resource) CRUDWithConfig(path string, resource interface{}, config CRUDConfig) *Resource {
+	parents := r.parents
+	if !r.config.Singular {
+		parents = append(parents[:len(parents):len(parents)], resourceParent{
+			param:     r.config.ParentIDParam,
+			validator: r.config.IDValidator,
+		})
+		path = "/:" + r.config.ParentIDParam + path
+	}
+	child := registerResource(r.group, r.path+path, resource, config, parents, r.config.Name+".")
+	r.children = append(r.children, child)
+	return child
+}
+
+// Info returns the description of the resource.
+func (r *Resource) Info() ResourceInfo {
+	info := ResourceInfo{
+		Name:      r.config.Name,
+		Path:      r.prefix + r.path,
+		IDParam:   r.memberParam(),
+		Verbs:     r.verbs,
+		ListQuery: r.config.ListQuery,
+		Fields:    r.config.Fields,
+		Routes:    r.routes,
+	}
+	_, info.SoftDelete = r.resource.(SoftDeleteSupported)
+	if r.typed != nil {
+		info.Type = r.typed.Type()
+		info.IDType = r.typed.IDType()
+	}
+	if resource, ok := r.resource.(interface{ jsonAPIType() string }); ok {
+		info.JSONAPIType = resource.jsonAPIType()
+	}
+	return info
+}
+
+// Children returns the nested resources registered under r.
+func (r *Resource) Children() []*Resource {
+	return r.children
+}
+
+// Name returns the prefix of the resource's route names.
+func (r *Resource) Name() string {
+	return r.config.Name
+}
+
+// Routes returns the routes registered for the resource.
+func (r *Resource) Routes() echo.Routes {
+	return r.routes
+}
+
+// ResourceURL returns the URL of the route registered with the given name (e.g. "tests.show").
+// When less IDs than path parameters are given, the leading parameters are taken from the current request
+// so a nested resource's handler only needs to give its own ID (e.g. ResourceURL(c, "projects.tasks.show", 42)).
+// When several routes have the name (e.g. the same resource registered in `/v1' and `/v2' groups),
+// the route sharing the longest path prefix with the current request's route is used.
+func ResourceURL(c *echo.Context, name string, ids ...any) (string, error) {
+	routes, err := c.Echo().Router().Routes().FilterByName(name)
+	if err != nil {
+		return "", fmt.Errorf("route %s not found", name)
+	}
+	route := routes[0]
+	current, longest := c.Path(), commonPrefixLen(c.Path(), route.Path)
+	for _, candidate := range routes[1:] {
+		if n := commonPrefixLen(current, candidate.Path); n > longest {
+			route, longest = candidate, n
+		}
+	}
+
+	n := len(route.Parameters) - len(ids)
+	if n < 0 {
+		return "", fmt.Errorf("route %s has %d parameters, got %d", name, len(route.Parameters), len(ids))
+	}
+
+	values := make([]any, len(route.Parameters))
+	for i, param := range route.Parameters {
+		v := c.Param(param)
+		if i >= n {
+			v = fmt.Sprint(ids[i-n])
+		}
+		values[i] = url.PathEscape(v)
+	}
+	return route.Reverse(values...), nil
+}
+
+func commonPrefixLen(a, b string) int {
+	n := min(len(a), len(b))
+	for i := range n {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// ParentIDs returns the IDs of the parent resources of a nested resource, outermost first.
+// e.g. [project_id] for `/projects/:project_id/tasks/:id'
+func ParentIDs(c *echo.Context) []string {
+	params, _ := c.Get(parentIDsContextKey).([]string)
+
+	ids := make([]string, len(params))
+	for i, param := range params {
+		ids[i] = c.Param(param)
+	}
+	return ids
+}
+
+func registerResource(group *echo.Group, path string, resource interface{}, config CRUDConfig, parents []resourceParent, namePrefix string) *Resource {
+	// Defaults
+	if config.IDParam == "" {
+		config.IDParam = DefaultCRUDConfig.IDParam
+	}
+	if len(config.UpdateMethods) == 0 {
+		config.UpdateMethods = DefaultCRUDConfig.UpdateMethods
+	}
+	if config.ParentIDParam == "" {
+		config.ParentIDParam = singular(path[strings.LastIndex(path, "/")+1:]) + "_" + config.IDParam
+	}
+	if config.MaxBulkSize <= 0 {
+		config.MaxBulkSize = DefaultMaxBulkSize
+	}
+	if config.Name == "" {
+		config.Name = namePrefix + path[strings.LastIndex(path, "/")+1:]
+	}
+	if config.CORS != nil && len(config.CORS.AllowOrigins) == 0 {
+		cors := *config.CORS
+		cors.AllowOrigins = DefaultCORSConfig.AllowOrigins
+		config.CORS = &cors
+	}
+	if config.CORS != nil && config.CORS.AllowCredentials && !config.CORS.UnsafeWildcardOriginWithAllowCredentials && slices.Contains(config.CORS.AllowOrigins, "*") {
+		panic("CORS wildcard origin with credentials requires UnsafeWildcardOriginWithAllowCredentials for " + path)
+	}
+	if _, ok := resource.(interface{ repositoryBacked() }); ok && config.Singular {
+		panic("Singular is not supported by typed resources for " + path)
+	}
+	for _, method := range config.UpdateMethods {
+		if method != http.MethodPatch && method != http.MethodPut {
+			panic("UpdateMethods only supports PATCH and PUT, got " + method)
+		}
+	}
+	params := map[string]bool{config.IDParam: true}
+	for _, parent := range parents {
+		if params[parent.param] {
+			panic("ID param " + parent.param + " is used by several levels of " + path)
+		}
+		params[parent.param] = true
+	}
+
+	r := &Resource{
+		group:    group,
+		path:     path,
+		config:   config,
+		parents:  parents,
+		resource: resource,
+		methods:  map[string][]string{},
+	}
+	r.typed, _ = resource.(TypedSupported)
+
+	if len(parents) > 0 {
+		r.collectionMW = append(r.collectionMW, withParents(parents))
+	}
+	r.memberMW = r.collectionMW[:len(r.collectionMW):len(r.collectionMW)]
+	if config.IDValidator != nil && !config.Singular {
+		r.memberMW = append(r.memberMW, validateID(config.IDParam, config.IDValidator))
+	}
+
+	member := r.memberPath()
+
+	createMW, listMW, showMW, writeMW, deleteMW, restoreMW := r.collectionMW, r.collectionMW, r.memberMW, r.memberMW, r.memberMW, r.memberMW
+	purgeMW, bulkCreateMW := r.memberMW, r.collectionMW
+	if _, ok := resource.(SoftDeleteSupported); ok {
+		listMW = append(listMW[:len(listMW):len(listMW)], withIncludeDeleted)
+		showMW = append(showMW[:len(showMW):len(showMW)], withIncludeDeleted)
+	}
+	if config.ListQuery != nil {
+		listMW = append(listMW[:len(listMW):len(listMW)], withListQuery(*config.ListQuery))
+	}
+	var policy *crudPolicy
+	if resource, ok := resource.(PolicySupported); ok {
+		policy = &crudPolicy{policy: resource, config: DefaultCRUDPolicyConfig, idParam: config.IDParam}
+		policy.purge, _ = resource.(PurgePolicySupported)
+		if config.Policy != nil {
+			policy.config = *config.Policy
+		}
+		if policy.config.ContextKey == "" {
+			policy.config.ContextKey = DefaultCRUDPolicyConfig.ContextKey
+		}
+
+		// Runs before the ETag and Fields middlewares so the denied requests do not reach the resource
+		createMW = append(createMW[:len(createMW):len(createMW)], policy.middleware(VerbCreate))
+		listMW = append(listMW[:len(listMW):len(listMW)], policy.middleware(VerbList))
+		showMW = append(showMW[:len(showMW):len(showMW)], policy.middleware(VerbShow))
+		writeMW = append(writeMW[:len(writeMW):len(writeMW)], policy.middleware(VerbUpdate))
+		deleteMW = append(deleteMW[:len(deleteMW):len(deleteMW)], policy.middleware(VerbDelete))
+		restoreMW = append(restoreMW[:len(restoreMW):len(restoreMW)], policy.middleware(VerbRestore))
+		bulkCreateMW = append(bulkCreateMW[:len(bulkCreateMW):len(bulkCreateMW)], policy.middleware(VerbBulkCreate))
+	}
+	if config.Fields != nil {
+		// Runs before the ETag middlewares so the entity tags do not depend on the selected fields
+		fields := withFields(*config.Fields)
+		createMW = append(createMW[:len(createMW):len(createMW)], fields)
+		listMW = append(listMW[:len(listMW):len(listMW)], fields)
+		showMW = append(showMW[:len(showMW):len(showMW)], fields)
+		writeMW = append(writeMW[:len(writeMW):len(writeMW)], fields)
+		deleteMW = append(deleteMW[:len(deleteMW):len(deleteMW)], fields)
+		restoreMW = append(restoreMW[:len(restoreMW):len(restoreMW)], fields)
+	}
+	if config.ETag {
+		var lookup func(c *echo.Context) (string, error)
+		switch resource := resource.(type) {
+		case ETagSupported:
+			lookup = resource.ETag
+			showMW = append(showMW[:len(showMW):len(showMW)], withETag(lookup))
+		case ShowSupported:
+			show := resource.Show
+			if config.Fields != nil && len(config.Fields.Hidden) > 0 {
+				show = withHiddenFields(hiddenFields(*config.Fields))(show)
+			}
+			lookup = showETag(show)
+			showMW = append(showMW[:len(showMW):len(showMW)], withBodyETag)
+		default:
+			panic("ETag requires ShowSupported or ETagSupported resource for " + path)
+		}
+		// The bulk items cannot carry preconditions, registering them would bypass the optimistic concurrency
+		_, bulkUpdate := resource.(BulkUpdateSupported)
+		_, bulkDelete := resource.(BulkDeleteSupported)
+		if (bulkUpdate || bulkDelete) && !config.Singular {
+			panic("ETag does not support BulkUpdate nor BulkDelete resource for " + path)
+		}
+		listMW = append(listMW[:len(listMW):len(listMW)], withBodyETag)
+		writeMW = append(writeMW[:len(writeMW):len(writeMW)], withIfMatch(lookup))
+		deleteMW = append(deleteMW[:len(deleteMW):len(deleteMW)], withIfMatch(lookup))
+	}
+	bulkMW := r.collectionMW
+	if config.Fields != nil && len(config.Fields.Hidden) > 0 {
+		// Runs after the ETag middlewares so the entity tags are not computed from the hidden fields
+		hidden := hiddenFields(*config.Fields)
+		mw := withHiddenFields(hidden)
+		createMW = append(createMW[:len(createMW):len(createMW)], mw)
+		listMW = append(listMW[:len(listMW):len(listMW)], mw)
+		showMW = append(showMW[:len(showMW):len(showMW)], mw)
+		writeMW = append(writeMW[:len(writeMW):len(writeMW)], mw)
+		deleteMW = append(deleteMW[:len(deleteMW):len(deleteMW)], mw)
+		restoreMW = append(restoreMW[:len(restoreMW):len(restoreMW)], mw)
+
+		mw = withHiddenFields(fieldTree{"results": fieldTree{"data": hidden}})
+		bulkCreateMW = append(bulkCreateMW[:len(bulkCreateMW):len(bulkCreateMW)], mw)
+		bulkMW = append(bulkMW[:len(bulkMW):len(bulkMW)], mw)
+	}
+
 	if resource, ok := resource.(CreateSupported); ok {
-		group.POST(path, resource.Create)
+		r.addRoute(VerbCreate, "", http.MethodPost, path, resource.Create, createMW)
 	}
-	if resource, ok := resource.(ListSupported); ok {
-		group.GET(path, resource.List)
+	if resource, ok := resource.(ListSupported); ok && !config.Singular {
+		r.addRoute(VerbList, "", http.MethodGet, path, resource.List, listMW)
 	}
 	if resource, ok := resource.(ShowSupported); ok {
-		group.GET(path+"/:id", resource.Show)
+		r.addRoute(VerbShow, "", http.MethodGet, member, resource.Show, showMW)
 	}
 	if resource, ok := resource.(UpdateSupported); ok {
-		group.PATCH(path+"/:id", resource.Update)
+		_, replace := resource.(ReplaceSupported)
+		for _, method := range config.UpdateMethods {
+			if method == http.MethodPut && replace {
+				continue
+			}
+			r.addRoute(VerbUpdate, "", method, member, resource.Update, writeMW)
+		}
+	}
+	if resource, ok := resource.(ReplaceSupported); ok {
+		r.addRoute(VerbReplace, "", http.MethodPut, member, resource.Replace, writeMW)
+	}
+	if soft, ok := resource.(SoftDeleteSupported); ok {
+		r.addRoute(VerbDelete, "", http.MethodDelete, member, soft.SoftDelete, deleteMW)
+	} else if resource, ok := resource.(DeleteSupported); ok {
+		r.addRoute(VerbDelete, "", http.MethodDelete, member, resource.Delete, deleteMW)
+	}
+	if resource, ok := resource.(RestoreSupported); ok {
+		r.addRoute(VerbRestore, "", http.MethodPost, member+restorePath, resource.Restore, restoreMW)
+	}
+	if resource, ok := resource.(PurgeSupported); ok {
+		// Only a middleware dedicated to Purge guards it, VerbWrite middlewares are not meant to authorize permanent deletions
+		if policy != nil {
+			purgeMW = append(purgeMW[:len(purgeMW):len(purgeMW)], policy.middleware(VerbPurge))
+		} else if !slices.ContainsFunc(config.Middlewares, func(mw VerbMiddleware) bool { return mw.Verbs == VerbPurge }) {
+			panic("Purge requires a VerbPurge middleware or a PolicySupported resource for " + path)
+		}
+		r.addRoute(VerbPurge, "", http.MethodDelete, member+purgePath, resource.Purge, purgeMW)
+	}
+
+	if resource, ok := resource.(BulkCreateSupported); ok && !config.Singular {
+		r.addRoute(VerbBulkCreate, "", http.MethodPost, path+bulkPath, bulkItems(resource.BulkCreate, config.MaxBulkSize), bulkCreateMW)
+	}
+	if resource, ok := resource.(BulkUpdateSupported); ok && !config.Singular {
+		handler := resource.BulkUpdate
+		if policy != nil {
+			handler = policy.bulkUpdate(handler)
+		}
+		r.addRoute(VerbBulkUpdate, "", http.MethodPatch, path+bulkPath, bulkItems(handler, config.MaxBulkSize), bulkMW)
+	}
+	if resource, ok := resource.(BulkDeleteSupported); ok && !config.Singular {
+		handler := resource.BulkDelete
+		if policy != nil {
+			handler = policy.bulkDelete(handler)
+		}
+		r.addRoute(VerbBulkDelete, "", http.MethodDelete, path, bulkIDs(handler, config.MaxBulkSize, config.IDValidator), bulkMW)
+	}
+
+	if resource, ok := resource.(ActionsSupported); ok {
+		for _, action := range resource.Actions() {
+			r.Action(action)
+		}
+	}
+
+	return r
+}
+
+// memberPath returns the path of the member routes, the path itself for singular resources.
+func (r *Resource) memberPath() string {
+	if r.config.Singular {
+		return r.path
+	}
+	return r.path + "/:" + r.config.IDParam
+}
+
+// memberParam returns the ID path parameter of the member routes, empty for singular resources.
+func (r *Resource) memberParam() string {
+	if r.config.Singular {
+		return ""
+	}
+	return r.config.IDParam
+}
+
+// addRoute registers a route of the resource, named after the verb or the custom action.
+// The middlewares of the verb/action are run before the given middlewares.
+// The first route of a path also registers the fallback handler of its other methods (405 and OPTIONS).
+func (r *Resource) addRoute(verb Verb, action, method, path string, handler echo.HandlerFunc, middlewares []echo.MiddlewareFunc) {
+	if action == "" {
+		action = verb.String()
+	}
+
+	var verbMW []echo.MiddlewareFunc
+	if r.config.CORS != nil {
+		verbMW = append(verbMW, withCORS(*r.config.CORS))
 	}
-	if resource, ok := resource.(DeleteSupported); ok {
-		group.DELETE(path+"/:id", resource.Delete)
+	for _, mw := range r.config.Middlewares {
+		if mw.Verbs&verb != 0 || (verb == VerbAction && slices.Contains(mw.Actions, action)) {
+			verbMW = append(verbMW, mw.Middlewares...)
+		}
+	}
+
+	route, err := r.group.AddRoute(echo.Route{
+		Method:      method,
+		Path:        path,
+		Name:        r.config.Name + "." + action,
+		Handler:     handler,
+		Middlewares: append(verbMW, middlewares...),
+	})
+	if err != nil {
+		panic(err)
+	}
+	r.routes = append(r.routes, route)
+	r.prefix = strings.TrimSuffix(route.Path, path)
+	r.verbs |= verb
+
+	if _, ok := r.methods[path]; !ok {
+		_, err := r.group.AddRoute(echo.Route{
+			Method:  echo.RouteNotFound,
+			Path:    path,
+			Name:    echo.NotFoundRouteName,
+			Handler: r.fallback(path),
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+	r.methods[path] = append(r.methods[path], method)
+}
+
+// withParents returns a middleware validating the parent IDs and exposing them to ParentIDs.
+func withParents(parents []resourceParent) echo.MiddlewareFunc {
+	params := make([]string, len(parents))
+	for i, parent := range parents {
+		params[i] = parent.param
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			for _, parent := range parents {
+				if parent.validator == nil {
+					continue
+				}
+				if err := checkID(c, parent.param, parent.validator); err != nil {
+					return err
+				}
+			}
+
+			c.Set(parentIDsContextKey, params)
+			return next(c)
+		}
+	}
+}
+
+// singular returns a naive singular form of the given english word.
+func singular(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "ses"), strings.HasSuffix(word, "xes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return strings.TrimSuffix(word, "s")
+	default:
+		return word
+	}
+}
+
+// ValidateUUID is an IDValidator accepting RFC 4122 textual UUIDs (e.g. 123e4567-e89b-12d3-a456-426614174000).
+func ValidateUUID(id string) error {
+	if !uuidPattern.MatchString(id) {
+		return errors.New("not a UUID")
+	}
+	return nil
+}
+
+// ValidateInteger is an IDValidator accepting base 10 integers.
+func ValidateInteger(id string) error {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return errors.New("not an integer")
+	}
+	return nil
+}
+
+// ValidateRegexp returns an IDValidator accepting IDs matching the given pattern.
+// It panics if the pattern does not compile.
+func ValidateRegexp(pattern string) IDValidator {
+	re := regexp.MustCompile(pattern)
+	return func(id string) error {
+		if !re.MatchString(id) {
+			return fmt.Errorf("does not match %s", pattern)
+		}
+		return nil
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// validateID returns a middleware rejecting requests with an invalid ID.
+func validateID(param string, validator IDValidator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			if err := checkID(c, param, validator); err != nil {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+func checkID(c *echo.Context, param string, validator IDValidator) error {
+	if err := validator(c.Param(param)); err != nil {
+		return echo.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("invalid %s: %s", param, err),
+		}.Wrap(err)
 	}
+	return nil
 }
@@ -0,0 +1,72 @@
+package middlewarex
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v5"
+)
+
+// ActionsSupported interface
+// It declares the custom actions of a resource, registered by CRUD along the built-in verbs.
+type ActionsSupported interface {
+	Actions() []Action
+}
+
+// Action is a custom action of a resource.
+// e.g. `POST /orders/:id/cancel' (member) or `GET /orders/search' (collection)
+type Action struct {
+	// Name is the name of the action, used as route name suffix (e.g. "orders.cancel").
+	// Required.
+	Name string
+
+	// Method is the HTTP method of the action.
+	// Optional. Default value POST.
+	Method string
+
+	// Path is the path of the action relative to the collection or member path.
+	// Optional. Default value "/" followed by the name.
+	Path string
+
+	// Member registers the action on the member path (`/path/:id/name') instead of the collection path (`/path/name').
+	Member bool
+
+	// Handler is the handler of the action.
+	// Required.
+	Handler echo.HandlerFunc
+
+	// Middlewares are the middlewares of the action.
+	// They run after the CRUDConfig.Middlewares matching the action.
+	// Optional.
+	Middlewares []echo.MiddlewareFunc
+}
+
+// Action registers a custom action on the resource.
+// It gets the parent IDs and ID validation of the built-in verbs and the CRUDConfig.Middlewares matching VerbAction or its name.
+// It panics if the action has no name or handler, or if its name is a built-in verb name.
+func (r *Resource) Action(action Action) *Resource {
+	// Defaults
+	if action.Method == "" {
+		action.Method = http.MethodPost
+	}
+	if action.Path == "" {
+		action.Path = "/" + action.Name
+	}
+	if action.Name == "" || action.Handler == nil {
+		panic("custom actions require a name and a handler")
+	}
+	if slices.Contains(verbNames, action.Name) {
+		panic("action name " + action.Name + " is reserved")
+	}
+
+	path := r.path + action.Path
+	middlewares := r.collectionMW
+	if action.Member {
+		path = r.memberPath() + action.Path
+		middlewares = r.memberMW
+	}
+	middlewares = append(middlewares[:len(middlewares):len(middlewares)], action.Middlewares...)
+
+	r.addRoute(VerbAction, action.Name, action.Method, path, action.Handler, middlewares)
+	return r
+}
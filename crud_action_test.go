@@ -0,0 +1,115 @@
+package middlewarex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+)
+
+type crudActionCtrl struct{}
+
+func (*crudActionCtrl) Show(c *echo.Context) error {
+	return c.String(http.StatusOK, "show "+c.Param("id"))
+}
+
+func (*crudActionCtrl) Actions() []middlewarex.Action {
+	return []middlewarex.Action{
+		{
+			Name:   "cancel",
+			Member: true,
+			Handler: func(c *echo.Context) error {
+				return c.String(http.StatusOK, "cancel "+c.Param("id"))
+			},
+		},
+		{
+			Name:   "search",
+			Method: http.MethodGet,
+			Handler: func(c *echo.Context) error {
+				return c.String(http.StatusOK, "search "+c.QueryParam("q"))
+			},
+		},
+	}
+}
+
+func TestCRUDActions(t *testing.T) {
+	deny := func(_ echo.HandlerFunc) echo.HandlerFunc {
+		return func(_ *echo.Context) error {
+			return echo.ErrForbidden
+		}
+	}
+
+	e := echo.New()
+	orders := middlewarex.CRUDWithConfig(e.Group(""), "/orders", &crudActionCtrl{}, middlewarex.CRUDConfig{
+		IDValidator: middlewarex.ValidateInteger,
+		Middlewares: []middlewarex.VerbMiddleware{
+			middlewarex.WithActionMiddleware([]string{"archive"}, deny),
+		},
+	})
+	orders.Action(middlewarex.Action{
+		Name:   "archive",
+		Member: true,
+		Path:   "/archives",
+		Handler: func(c *echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		},
+	})
+	orders.Action(middlewarex.Action{
+		Name:        "export",
+		Method:      http.MethodGet,
+		Handler:     func(c *echo.Context) error { return c.NoContent(http.StatusOK) },
+		Middlewares: []echo.MiddlewareFunc{deny},
+	})
+
+	names := map[string]string{}
+	for _, route := range e.Router().Routes() {
+		names[route.Name] = route.Method + " " + route.Path
+	}
+	for name, v := range map[string]string{
+		"orders.show":    "GET /orders/:id",
+		"orders.cancel":  "POST /orders/:id/cancel",
+		"orders.search":  "GET /orders/search",
+		"orders.archive": "POST /orders/:id/archives",
+		"orders.export":  "GET /orders/export",
+	} {
+		if names[name] != v {
+			t.Fatalf("Expecting %s route to be %s but got %q", name, v, names[name])
+		}
+	}
+	if orders.Info().Verbs != middlewarex.VerbShow|middlewarex.VerbAction {
+		t.Fatalf("Expecting show|action verbs but got %s", orders.Info().Verbs)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{method: http.MethodPost, path: "/orders/1/cancel", code: http.StatusOK, body: "cancel 1"},
+		{method: http.MethodPost, path: "/orders/one/cancel", code: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/orders/search?q=shoes", code: http.StatusOK, body: "search shoes"},
+		{method: http.MethodGet, path: "/orders/2", code: http.StatusOK, body: "show 2"},
+		{method: http.MethodPost, path: "/orders/1/archives", code: http.StatusForbidden},
+		{method: http.MethodGet, path: "/orders/export", code: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(test.method, test.path, nil))
+		if rec.Code != test.code {
+			t.Fatalf("Expecting %d for %s %s but got %d", test.code, test.method, test.path, rec.Code)
+		}
+		if test.body != "" && rec.Body.String() != test.body {
+			t.Fatalf("Expecting %q for %s %s but got %q", test.body, test.method, test.path, rec.Body.String())
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expecting a panic for a reserved action name")
+		}
+	}()
+	orders.Action(middlewarex.Action{Name: "list", Handler: func(*echo.Context) error { return nil }})
+}
@@ -0,0 +1,157 @@
+package middlewarex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+// BulkCreateSupported interface
+// Registered on `POST /path/_bulk' with the JSON array items of the request body.
+type BulkCreateSupported interface {
+	BulkCreate(c *echo.Context, items []json.RawMessage) ([]BulkResult, error)
+}
+
+// BulkUpdateSupported interface
+// Registered on `PATCH /path/_bulk' with the JSON array items of the request body.
+type BulkUpdateSupported interface {
+	BulkUpdate(c *echo.Context, items []json.RawMessage) ([]BulkResult, error)
+}
+
+// BulkDeleteSupported interface
+// Registered on `DELETE /path?ids=1,2,3' with the given IDs.
+type BulkDeleteSupported interface {
+	BulkDelete(c *echo.Context, ids []string) ([]BulkResult, error)
+}
+
+// BulkResult is the result of one item of a bulk operation.
+type BulkResult struct {
+	// ID is the ID of the item, if any.
+	ID string `json:"id,omitempty"`
+	// Status is the HTTP status code of the item.
+	Status int `json:"status"`
+	// Data is the resulting item, if any.
+	Data any `json:"data,omitempty"`
+	// Error is the error message of a failed item.
+	Error string `json:"error,omitempty"`
+}
+
+// BulkResponse is the response body of bulk operations.
+type BulkResponse struct {
+	Results []BulkResult `json:"results"`
+}
+
+// DefaultMaxBulkSize is the default maximum number of items of a bulk operation.
+const DefaultMaxBulkSize = 100
+
+const bulkPath = "/_bulk"
+
+// BulkError returns the failed BulkResult of the given item's error.
+// The status is the one of HTTP errors, "500 - Internal Server Error" otherwise.
+func BulkError(id string, err error) BulkResult {
+	status := echo.StatusCode(err)
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	message := http.StatusText(status)
+	var herr *echo.HTTPError
+	if errors.As(err, &herr) && herr.Message != "" {
+		message = herr.Message
+	}
+
+	return BulkResult{
+		ID:     id,
+		Status: status,
+		Error:  message,
+	}
+}
+
+// Failed returns true if the item has failed.
+func (r BulkResult) Failed() bool {
+	return r.Status < 200 || r.Status > 299
+}
+
+// bulkItems returns a handler decoding the JSON array of items of the request body.
+func bulkItems(handler func(*echo.Context, []json.RawMessage) ([]BulkResult, error), maxSize int) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		var items []json.RawMessage
+		if err := json.NewDecoder(c.Request().Body).Decode(&items); err != nil {
+			return echo.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: "bulk body must be a JSON array",
+			}.Wrap(err)
+		}
+		if err := checkBulkSize(len(items), maxSize); err != nil {
+			return err
+		}
+
+		results, err := handler(c, items)
+		if err != nil {
+			return err
+		}
+		return renderBulk(c, results)
+	}
+}
+
+// bulkIDs returns a handler parsing the comma separated IDs of the `ids' query parameter.
+func bulkIDs(handler func(*echo.Context, []string) ([]BulkResult, error), maxSize int, validator IDValidator) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		var ids []string
+		for id := range strings.SplitSeq(c.QueryParam("ids"), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if err := checkBulkSize(len(ids), maxSize); err != nil {
+			return err
+		}
+
+		if validator != nil {
+			for _, id := range ids {
+				if err := validator(id); err != nil {
+					return echo.HTTPError{
+						Code:    http.StatusBadRequest,
+						Message: fmt.Sprintf("invalid ids: %s: %s", id, err),
+					}.Wrap(err)
+				}
+			}
+		}
+
+		results, err := handler(c, ids)
+		if err != nil {
+			return err
+		}
+		return renderBulk(c, results)
+	}
+}
+
+func checkBulkSize(size, maxSize int) error {
+	if size == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "bulk operations require at least one item")
+	}
+	if size > maxSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("bulk operations are limited to %d items", maxSize))
+	}
+	return nil
+}
+
+// renderBulk renders the results with "200 - OK" when all items succeeded, "207 - Multi-Status" otherwise.
+func renderBulk(c *echo.Context, results []BulkResult) error {
+	if results == nil {
+		results = []BulkResult{}
+	}
+
+	status := http.StatusOK
+	for _, result := range results {
+		if result.Failed() {
+			status = http.StatusMultiStatus
+			break
+		}
+	}
+	return c.JSON(status, BulkResponse{Results: results})
+}
@@ -0,0 +1,109 @@
+package middlewarex_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+type crudBulkCtrl struct{}
+
+func (*crudBulkCtrl) Show(c *echo.Context) error {
+	return c.String(http.StatusOK, c.Param("id"))
+}
+
+func (*crudBulkCtrl) BulkCreate(_ *echo.Context, items []json.RawMessage) ([]middlewarex.BulkResult, error) {
+	results := make([]middlewarex.BulkResult, len(items))
+	for i, item := range items {
+		var v struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(item, &v); err != nil || v.Name == "" {
+			results[i] = middlewarex.BulkError("", echo.NewHTTPError(http.StatusUnprocessableEntity, "name is required"))
+			continue
+		}
+		results[i] = middlewarex.BulkResult{Status: http.StatusCreated, Data: v}
+	}
+	return results, nil
+}
+
+func (*crudBulkCtrl) BulkUpdate(_ *echo.Context, _ []json.RawMessage) ([]middlewarex.BulkResult, error) {
+	return nil, echo.ErrForbidden
+}
+
+func (*crudBulkCtrl) BulkDelete(_ *echo.Context, ids []string) ([]middlewarex.BulkResult, error) {
+	results := make([]middlewarex.BulkResult, len(ids))
+	for i, id := range ids {
+		if id == "42" {
+			results[i] = middlewarex.BulkError(id, errors.New("database is down"))
+			continue
+		}
+		results[i] = middlewarex.BulkResult{ID: id, Status: http.StatusNoContent}
+	}
+	return results, nil
+}
+
+func TestCRUDBulk(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUDWithConfig(e.Group(""), "/tests", &crudBulkCtrl{}, middlewarex.CRUDConfig{
+		IDValidator: middlewarex.ValidateInteger,
+		MaxBulkSize: 3,
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		code   int
+		result string
+	}{
+		{
+			method: http.MethodPost,
+			path:   "/tests/_bulk",
+			body:   `[{"name":"a"},{"name":"b"}]`,
+			code:   http.StatusOK,
+			result: `{"results":[{"status":201,"data":{"name":"a"}},{"status":201,"data":{"name":"b"}}]}`,
+		},
+		{
+			method: http.MethodPost,
+			path:   "/tests/_bulk",
+			body:   `[{"name":"a"},{}]`,
+			code:   http.StatusMultiStatus,
+			result: `{"results":[{"status":201,"data":{"name":"a"}},{"status":422,"error":"name is required"}]}`,
+		},
+		{method: http.MethodPost, path: "/tests/_bulk", body: `[{},{},{},{}]`, code: http.StatusRequestEntityTooLarge},
+		{method: http.MethodPost, path: "/tests/_bulk", body: `[]`, code: http.StatusBadRequest},
+		{method: http.MethodPost, path: "/tests/_bulk", body: `{"name":"a"}`, code: http.StatusBadRequest},
+		{method: http.MethodPatch, path: "/tests/_bulk", body: `[{}]`, code: http.StatusForbidden},
+		{
+			method: http.MethodDelete,
+			path:   "/tests?ids=1,42",
+			code:   http.StatusMultiStatus,
+			result: `{"results":[{"id":"1","status":204},{"id":"42","status":500,"error":"Internal Server Error"}]}`,
+		},
+		{method: http.MethodDelete, path: "/tests?ids=1,a", code: http.StatusBadRequest},
+		{method: http.MethodDelete, path: "/tests?ids=1,2,3,4", code: http.StatusRequestEntityTooLarge},
+		{method: http.MethodDelete, path: "/tests", code: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/tests/7", code: http.StatusOK, result: "7"},
+	}
+
+	for _, test := range tests {
+		name := test.method + " " + test.path
+		rec := serve(e, test.method, test.path, test.body)
+		assert.Equal(t, test.code, rec.Code, name)
+		if test.result == "" {
+			continue
+		}
+		if strings.HasPrefix(test.result, "{") {
+			assert.JSONEq(t, test.result, rec.Body.String(), name)
+		} else {
+			assert.Equal(t, test.result, rec.Body.String(), name)
+		}
+	}
+}
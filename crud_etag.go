@@ -0,0 +1,200 @@
+package middlewarex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+// ETagSupported interface
+// ETag returns the current entity tag of the member identified by the request's ID.
+// It is used by CRUD (see CRUDConfig.ETag) instead of hashing the Show response.
+type ETagSupported interface {
+	ETag(c *echo.Context) (string, error)
+}
+
+// Versioned interface
+// It is implemented by the values of typed resources providing their own entity tag (e.g. a version number).
+// Other values are tagged with a hash of their JSON representation.
+type Versioned interface {
+	ETag() string
+}
+
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// ErrPreconditionRequired is returned when a conditional request has no If-Match header.
+var ErrPreconditionRequired = echo.NewHTTPError(http.StatusPreconditionRequired, "missing If-Match header")
+
+// ErrPreconditionFailed is returned when the If-Match header does not match the current entity tag.
+var ErrPreconditionFailed = echo.NewHTTPError(http.StatusPreconditionFailed, "entity tag mismatch")
+
+// EntityTag returns the quoted entity tag of the given value.
+// Already quoted (or weak) entity tags are returned as is.
+func EntityTag(v string) string {
+	if strings.HasPrefix(v, `"`) || strings.HasPrefix(v, `W/"`) {
+		return v
+	}
+	return `"` + v + `"`
+}
+
+// ValueETag returns the entity tag of the given value: its Versioned tag or a hash of its JSON representation.
+func ValueETag(v any) (string, error) {
+	if v, ok := v.(Versioned); ok {
+		return EntityTag(v.ETag()), nil
+	}
+
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return hashETag(payload), nil
+}
+
+func hashETag(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return EntityTag(hex.EncodeToString(sum[:16]))
+}
+
+// etagMatch returns true if the given If-Match or If-None-Match header value matches the entity tag.
+// Weak entity tags never match with strong comparison.
+func etagMatch(header, etag string, weak bool) bool {
+	for tag := range strings.SplitSeq(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+			etag = strings.TrimPrefix(etag, "W/")
+		} else if strings.HasPrefix(tag, "W/") || strings.HasPrefix(etag, "W/") {
+			continue
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// withETag returns a middleware setting the ETag of a member from the given lookup
+// and answering "304 - Not Modified" when it matches the If-None-Match header.
+func withETag(lookup func(c *echo.Context) (string, error)) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			etag, err := lookup(c)
+			if err != nil {
+				return err
+			}
+
+			c.Response().Header().Set(headerETag, etag)
+			if etagMatch(c.Request().Header.Get(headerIfNoneMatch), etag, true) {
+				return c.NoContent(http.StatusNotModified)
+			}
+			return next(c)
+		}
+	}
+}
+
+// withBodyETag returns a middleware setting the ETag of successful responses to the hash of their body
+// and answering "304 - Not Modified" when it matches the If-None-Match header.
+func withBodyETag(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		w := c.Response()
+		buffer := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		c.SetResponse(buffer)
+		err := next(c)
+		c.SetResponse(w)
+		if err != nil {
+			return err
+		}
+
+		if buffer.status == http.StatusOK {
+			etag := hashETag(buffer.body.Bytes())
+			w.Header().Set(headerETag, etag)
+			if etagMatch(c.Request().Header.Get(headerIfNoneMatch), etag, true) {
+				return c.NoContent(http.StatusNotModified)
+			}
+		}
+
+		w.WriteHeader(buffer.status)
+		_, err = w.Write(buffer.body.Bytes())
+		return err
+	}
+}
+
+// withIfMatch returns a middleware requiring an If-Match header matching the current entity tag of the member.
+// It answers "428 - Precondition Required" when the header is missing and "412 - Precondition Failed" on mismatch.
+func withIfMatch(lookup func(c *echo.Context) (string, error)) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			header := c.Request().Header.Get(headerIfMatch)
+			if header == "" {
+				return ErrPreconditionRequired
+			}
+
+			etag, err := lookup(c)
+			if err != nil {
+				return err
+			}
+			if !etagMatch(header, etag, false) {
+				return ErrPreconditionFailed
+			}
+			return next(c)
+		}
+	}
+}
+
+// showETag returns a lookup hashing the body rendered by the given Show handler.
+func showETag(show echo.HandlerFunc) func(c *echo.Context) (string, error) {
+	return func(c *echo.Context) (string, error) {
+		w := c.Response()
+		buffer := &bufferedResponse{ResponseWriter: w, header: http.Header{}, status: http.StatusOK}
+		c.SetResponse(buffer)
+		err := show(c)
+		c.SetResponse(w)
+		if err != nil {
+			return "", err
+		}
+
+		if buffer.status != http.StatusOK {
+			return "", echo.NewHTTPError(buffer.status, http.StatusText(buffer.status))
+		}
+		return hashETag(buffer.body.Bytes()), nil
+	}
+}
+
+// bufferedResponse is a http.ResponseWriter keeping the status and the body in memory.
+type bufferedResponse struct {
+	http.ResponseWriter
+	header http.Header // Optional, headers are written to the wrapped response when nil.
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponse) Header() http.Header {
+	if w.header != nil {
+		return w.header
+	}
+	return w.ResponseWriter.Header()
+}
+
+func (w *bufferedResponse) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedResponse) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedResponse) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
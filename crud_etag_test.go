@@ -0,0 +1,134 @@
+package middlewarex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+type release struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+func (r release) ETag() string {
+	return "v" + strconv.Itoa(r.Version)
+}
+
+type crudETagCtrl struct {
+	name string
+}
+
+func (ctrl *crudETagCtrl) List(c *echo.Context) error {
+	return c.String(http.StatusOK, "["+ctrl.name+"]")
+}
+
+func (ctrl *crudETagCtrl) Show(c *echo.Context) error {
+	if c.Param("id") != "1" {
+		return echo.ErrNotFound
+	}
+	return c.String(http.StatusOK, ctrl.name)
+}
+
+func (ctrl *crudETagCtrl) Update(c *echo.Context) error {
+	ctrl.name = c.QueryParam("name")
+	return c.NoContent(http.StatusNoContent)
+}
+
+func serveConditional(e *echo.Echo, method, path, header, etag string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if etag != "" {
+		req.Header.Set(header, etag)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCRUDOfETag(t *testing.T) {
+	e := echo.New()
+	config := middlewarex.TypedCRUDConfig[release, int]{}
+	config.ETag = true
+	middlewarex.CRUDOfWithConfig(e.Group(""), "/releases", middlewarex.NewMemoryRepository[release, int](), config)
+
+	rec := serve(e, http.MethodPost, "/releases", `{"name":"1.0"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `"v1"`, rec.Header().Get("ETag"))
+
+	rec = serveConditional(e, http.MethodGet, "/releases/1", "If-None-Match", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"v1"`, rec.Header().Get("ETag"))
+
+	rec = serveConditional(e, http.MethodGet, "/releases/1", "If-None-Match", `"v0", W/"v1"`)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = serve(e, http.MethodPatch, "/releases/1", `{"name":"1.1"}`)
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+
+	rec = serveConditional(e, http.MethodPatch, "/releases/1", "If-Match", `"v0"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = serveConditional(e, http.MethodPatch, "/releases/1", "If-Match", `W/"v1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code, "weak entity tags never match If-Match")
+
+	rec = serveConditional(e, http.MethodPatch, "/releases/1", "If-Match", `"v1"`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"v2"`, rec.Header().Get("ETag"))
+
+	rec = serveConditional(e, http.MethodDelete, "/releases/1", "If-Match", `"v1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = serveConditional(e, http.MethodDelete, "/releases/2", "If-Match", `*`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serveConditional(e, http.MethodDelete, "/releases/1", "If-Match", `*`)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestCRUDBodyETag(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUDWithConfig(e.Group(""), "/tests", &crudETagCtrl{name: "a"}, middlewarex.CRUDConfig{ETag: true})
+
+	rec := serveConditional(e, http.MethodGet, "/tests/1", "If-None-Match", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "a", rec.Body.String())
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	rec = serveConditional(e, http.MethodGet, "/tests/1", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = serveConditional(e, http.MethodGet, "/tests/2", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	list := serveConditional(e, http.MethodGet, "/tests", "If-None-Match", "")
+	assert.Equal(t, http.StatusOK, list.Code)
+	assert.Equal(t, "[a]", list.Body.String())
+	rec = serveConditional(e, http.MethodGet, "/tests", "If-None-Match", list.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = serveConditional(e, http.MethodPatch, "/tests/1?name=b", "If-Match", etag)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = serveConditional(e, http.MethodPatch, "/tests/1?name=c", "If-Match", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = serveConditional(e, http.MethodGet, "/tests/1", "If-None-Match", etag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "b", rec.Body.String())
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+
+	assert.Panics(t, func() {
+		middlewarex.CRUDWithConfig(e.Group(""), "/lists", &crudListQueryCtrl{}, middlewarex.CRUDConfig{ETag: true})
+	})
+	assert.PanicsWithValue(t, "ETag does not support BulkUpdate nor BulkDelete resource for /bulks", func() {
+		middlewarex.CRUDWithConfig(e.Group(""), "/bulks", &crudPolicyCtrl{}, middlewarex.CRUDConfig{ETag: true})
+	})
+}
@@ -0,0 +1,259 @@
+package middlewarex
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+// FieldsConfig defines the field selection of CRUD responses with the `?fields=id,name,owner.email' query parameter.
+// The fields are dotted paths in the JSON output, the paths of arrays apply to each of their items.
+type FieldsConfig struct {
+	// Allowed is the list of the selectable fields, selecting a field also allows its children (e.g. "owner" allows "owner.email").
+	// Other fields are rejected with "400 - Bad Request" error.
+	// Optional. Default value allows all the fields.
+	Allowed []string
+
+	// Hidden is the list of the fields removed from all the responses and from the OpenAPI schemas,
+	// the entity tags are computed without them. They cannot be selected, filtered, sorted nor referenced by JSON Patch operations.
+	// Optional.
+	Hidden []string
+}
+
+const hiddenFieldsContextKey = "middlewarex.crud.hidden_fields"
+
+// fieldTree is a set of dotted paths, a nil subtree selects the whole field.
+type fieldTree map[string]fieldTree
+
+func (t fieldTree) add(path []string) {
+	child, ok := t[path[0]]
+	switch {
+	case len(path) == 1:
+		t[path[0]] = nil
+	case ok && child == nil:
+		// The whole field is already selected
+	default:
+		if !ok {
+			child = fieldTree{}
+			t[path[0]] = child
+		}
+		child.add(path[1:])
+	}
+}
+
+// filter returns v restricted to the fields of the tree.
+func (t fieldTree) filter(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		filtered := make(map[string]any, len(t))
+		for k, child := range t {
+			if e, ok := v[k]; ok {
+				if child != nil {
+					e = child.filter(e)
+				}
+				filtered[k] = e
+			}
+		}
+		return filtered
+	case []any:
+		for i := range v {
+			v[i] = t.filter(v[i])
+		}
+	}
+	return v
+}
+
+// remove removes the fields of the tree from v.
+func (t fieldTree) remove(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			if child == nil {
+				delete(v, k)
+			} else if e, ok := v[k]; ok {
+				child.remove(e)
+			}
+		}
+	case []any:
+		for _, e := range v {
+			t.remove(e)
+		}
+	}
+}
+
+// pointer returns whether the JSON Pointer tokens reference a field of the tree (or one of its children)
+// and whether they reference a parent of a field of the tree. Array indexes apply to each item.
+func (t fieldTree) pointer(path []string) (within, parent bool) {
+	node := t
+	for _, token := range path {
+		child, ok := node[token]
+		switch {
+		case ok && child == nil:
+			return true, false
+		case ok:
+			node = child
+		case token == "-":
+		default:
+			if _, err := strconv.Atoi(token); err != nil {
+				return false, false
+			}
+		}
+	}
+	return false, len(node) > 0
+}
+
+// withFields returns a middleware filtering the JSON responses with the `?fields=' query parameter.
+// The hidden fields are rejected from the List filters and sorts and from the JSON Patch operations (see PatchJSON),
+// they are removed from the responses by withHiddenFields.
+func withFields(config FieldsConfig) echo.MiddlewareFunc {
+	hidden := hiddenFields(config)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			selected, err := parseFields(c, config)
+			if err != nil {
+				return err
+			}
+			if q, ok := ListQueryFrom(c); ok {
+				for field := range q.Filter {
+					if fieldHidden(field, config) {
+						return echo.NewHTTPError(http.StatusBadRequest, "filter is not allowed on field "+field)
+					}
+				}
+				for _, s := range q.Sort {
+					if fieldHidden(s.Field, config) {
+						return echo.NewHTTPError(http.StatusBadRequest, "sort is not allowed on field "+s.Field)
+					}
+				}
+			}
+			if len(hidden) > 0 {
+				c.Set(hiddenFieldsContextKey, hidden)
+			}
+			if selected == nil {
+				return next(c)
+			}
+
+			return rewriteResponse(c, next, selected.filter, nil)
+		}
+	}
+}
+
+// withHiddenFields returns a middleware removing the hidden fields from the JSON and JSON:API responses.
+// It runs after the ETag middlewares so the entity tags are not computed from the hidden fields.
+func withHiddenFields(hidden fieldTree) echo.MiddlewareFunc {
+	remove := func(tree fieldTree) func(v any) any {
+		return func(v any) any {
+			tree.remove(v)
+			return v
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			// The fields of JSON:API documents are the attributes of the resource objects (see `?fields[type]=')
+			return rewriteResponse(c, next, remove(hidden), remove(fieldTree{"data": fieldTree{"attributes": hidden}}))
+		}
+	}
+}
+
+// hiddenFields returns the tree of the hidden fields of the config.
+func hiddenFields(config FieldsConfig) fieldTree {
+	hidden := fieldTree{}
+	for _, field := range config.Hidden {
+		hidden.add(strings.Split(field, "."))
+	}
+	return hidden
+}
+
+// rewriteResponse rewrites the successful JSON responses of next, and the JSON:API ones when rewriteJSONAPI is given.
+func rewriteResponse(c *echo.Context, next echo.HandlerFunc, rewrite, rewriteJSONAPI func(v any) any) error {
+	w := c.Response()
+	buffer := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+	c.SetResponse(buffer)
+	err := next(c)
+	c.SetResponse(w)
+	if err != nil {
+		return err
+	}
+
+	body := buffer.body.Bytes()
+	mediatype, _, _ := mime.ParseMediaType(w.Header().Get(echo.HeaderContentType))
+	if buffer.status/100 == 2 && len(body) > 0 {
+		switch {
+		case mediatype == echo.MIMEApplicationJSON:
+			body, err = rewriteJSON(body, rewrite)
+		case mediatype == MIMEApplicationJSONAPI && rewriteJSONAPI != nil:
+			body, err = rewriteJSON(body, rewriteJSONAPI)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	w.WriteHeader(buffer.status)
+	_, err = w.Write(body)
+	return err
+}
+
+// rewriteJSON decodes the JSON payload, rewrites its value and encodes it back.
+func rewriteJSON(payload []byte, rewrite func(v any) any) ([]byte, error) {
+	var v any
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(rewrite(v))
+}
+
+// parseFields parses the `?fields=' query parameter, it returns nil when no fields are selected.
+func parseFields(c *echo.Context, config FieldsConfig) (fieldTree, error) {
+	params := c.QueryParams()
+	if !params.Has("fields") {
+		return nil, nil
+	}
+
+	selected := fieldTree{}
+	for field := range strings.SplitSeq(params.Get("fields"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		path := strings.Split(field, ".")
+		if !fieldAllowed(field, config) || slices.Contains(path, "") {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "field "+field+" is not allowed")
+		}
+		selected.add(path)
+	}
+	if len(selected) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "fields must not be empty")
+	}
+	return selected, nil
+}
+
+// fieldAllowed returns true if the field or one of its parents is allowed and neither is hidden.
+func fieldAllowed(field string, config FieldsConfig) bool {
+	within := func(parent string) bool {
+		return field == parent || strings.HasPrefix(field, parent+".")
+	}
+	if slices.ContainsFunc(config.Hidden, within) {
+		return false
+	}
+	return len(config.Allowed) == 0 || slices.ContainsFunc(config.Allowed, within)
+}
+
+// fieldHidden returns true if the field or one of its parents is hidden, ignoring the case like the repositories do.
+func fieldHidden(field string, config FieldsConfig) bool {
+	field = strings.ToLower(field)
+	return slices.ContainsFunc(config.Hidden, func(parent string) bool {
+		parent = strings.ToLower(parent)
+		return field == parent || strings.HasPrefix(field, parent+".")
+	})
+}
@@ -0,0 +1,202 @@
+package middlewarex_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+type crudFieldsCtrl struct{}
+
+var crudFieldsUser = map[string]any{
+	"id":       1,
+	"name":     "Alice",
+	"password": "s3cr3t",
+	"owner":    map[string]any{"email": "bob@example.com", "token": "t0k3n", "phone": "555"},
+	"tags":     []any{map[string]any{"name": "admin", "internal": true}},
+}
+
+func (*crudFieldsCtrl) Create(c *echo.Context) error {
+	return c.JSON(http.StatusCreated, crudFieldsUser)
+}
+
+func (*crudFieldsCtrl) List(c *echo.Context) error {
+	return c.JSON(http.StatusOK, []any{crudFieldsUser})
+}
+
+func (*crudFieldsCtrl) Show(c *echo.Context) error {
+	return c.JSON(http.StatusOK, crudFieldsUser)
+}
+
+func (*crudFieldsCtrl) BulkCreate(_ *echo.Context, items []json.RawMessage) ([]middlewarex.BulkResult, error) {
+	results := make([]middlewarex.BulkResult, len(items))
+	for i := range items {
+		results[i] = middlewarex.BulkResult{ID: "1", Status: http.StatusCreated, Data: crudFieldsUser}
+	}
+	return results, nil
+}
+
+func (*crudFieldsCtrl) Delete(c *echo.Context) error {
+	return c.String(http.StatusOK, "password")
+}
+
+func TestCRUDFields(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUDWithConfig(e.Group(""), "/users", &crudFieldsCtrl{}, middlewarex.CRUDConfig{
+		Fields: &middlewarex.FieldsConfig{
+			Allowed: []string{"id", "name", "owner", "tags"},
+			Hidden:  []string{"password", "owner.token", "tags.internal"},
+		},
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		exp    string
+	}{
+		{
+			method: http.MethodGet, path: "/users/1", code: http.StatusOK,
+			exp: `{"id":1,"name":"Alice","owner":{"email":"bob@example.com","phone":"555"},"tags":[{"name":"admin"}]}`,
+		},
+		{method: http.MethodGet, path: "/users?fields=id,name,owner.email", code: http.StatusOK, exp: `[{"id":1,"name":"Alice","owner":{"email":"bob@example.com"}}]`},
+		{method: http.MethodGet, path: "/users/1?fields=owner,owner.email", code: http.StatusOK, exp: `{"owner":{"email":"bob@example.com","phone":"555"}}`},
+		{method: http.MethodGet, path: "/users/1?fields=tags.name,unknown.field", code: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/users/1?fields=tags.name", code: http.StatusOK, exp: `{"tags":[{"name":"admin"}]}`},
+		{method: http.MethodPost, path: "/users?fields=name", code: http.StatusCreated, exp: `{"name":"Alice"}`},
+		{method: http.MethodGet, path: "/users/1?fields=password", code: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/users/1?fields=owner.token", code: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/users/1?fields=owner..email", code: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/users/1?fields=", code: http.StatusBadRequest},
+		{method: http.MethodDelete, path: "/users/1", code: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		rec := serve(e, test.method, test.path, "")
+		info := test.method + " " + test.path
+		assert.Equal(t, test.code, rec.Code, info)
+		if test.exp != "" {
+			assert.JSONEq(t, test.exp, rec.Body.String(), info)
+		}
+	}
+}
+
+func TestCRUDOfJSONAPIHiddenFields(t *testing.T) {
+	e := echo.New()
+	config := middlewarex.TypedCRUDConfig[book, int]{JSONAPI: &middlewarex.JSONAPIConfig[book]{Type: "books"}}
+	config.Fields = &middlewarex.FieldsConfig{Hidden: []string{"author"}}
+	middlewarex.CRUDOfWithConfig(e.Group(""), "/books", newBookRepository(book{ID: 1, Title: "Dune", Author: "Frank Herbert"}), config)
+
+	rec := serve(e, http.MethodGet, "/books", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":[{"type":"books","id":"1","attributes":{"title":"Dune"}}],"links":{"self":"/books"}}`, rec.Body.String())
+}
+
+func TestCRUDOfHiddenFieldsLeaks(t *testing.T) {
+	type account struct {
+		ID     int    `json:"id"`
+		Name   string `json:"name"`
+		Secret string `json:"secret"`
+	}
+
+	repository := middlewarex.NewMemoryRepository[account, int]()
+	assert.NoError(t, repository.Create(context.Background(), &account{Name: "a", Secret: "s3cr3t"}))
+	e := echo.New()
+	config := middlewarex.TypedCRUDConfig[account, int]{}
+	config.ListQuery = &middlewarex.ListQueryConfig{}
+	config.Fields = &middlewarex.FieldsConfig{Hidden: []string{"secret"}}
+	middlewarex.CRUDOfWithConfig(e.Group(""), "/accounts", repository, config)
+
+	// Filtering and sorting on hidden fields would be an oracle
+	for _, path := range []string{"/accounts?filter[secret]=s3cr3t", "/accounts?filter[Secret]=s3cr3t", "/accounts?sort=-secret"} {
+		rec := serve(e, http.MethodGet, path, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, path)
+	}
+	rec := serve(e, http.MethodGet, "/accounts?filter[name]=a&sort=name", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// JSON Patch operations reading or writing hidden fields
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/accounts/1", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, middlewarex.MIMEApplicationJSONPatch)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	for _, body := range []string{
+		`[{"op":"copy","from":"/secret","path":"/name"}]`,
+		`[{"op":"move","from":"/secret","path":"/name"}]`,
+		`[{"op":"copy","from":"","path":"/name"}]`,
+		`[{"op":"test","path":"/secret","value":"s3cr3t"}]`,
+		`[{"op":"test","path":"","value":{"id":1,"name":"a","secret":"s3cr3t"}}]`,
+		`[{"op":"replace","path":"/secret","value":"x"}]`,
+		`[{"op":"remove","path":"/secret"}]`,
+	} {
+		rec := patch(body)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, body)
+		assert.NotContains(t, rec.Body.String(), "s3cr3t", body)
+	}
+
+	rec = patch(`[{"op":"copy","from":"/name","path":"/name"}]`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":1,"name":"a"}`, rec.Body.String())
+
+	v, err := repository.Get(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t", v.Secret)
+}
+
+func TestCRUDHiddenFieldsBulkAndETag(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUDWithConfig(e.Group(""), "/users", &crudFieldsCtrl{}, middlewarex.CRUDConfig{
+		ETag:   true,
+		Fields: &middlewarex.FieldsConfig{Hidden: []string{"password", "owner.token", "tags.internal"}},
+	})
+
+	rec := serve(e, http.MethodPost, "/users/_bulk", `[{"name":"Alice"}]`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"results":[{"id":"1","status":201,"data":{
+		"id":1,"name":"Alice","owner":{"email":"bob@example.com","phone":"555"},"tags":[{"name":"admin"}]
+	}}]}`, rec.Body.String())
+
+	// The entity tags are the hashes of the bodies without the hidden fields
+	for _, path := range []string{"/users", "/users/1"} {
+		rec = serve(e, http.MethodGet, path, "")
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.NotContains(t, rec.Body.String(), "s3cr3t", path)
+		sum := sha256.Sum256(rec.Body.Bytes())
+		assert.Equal(t, `"`+hex.EncodeToString(sum[:16])+`"`, rec.Header().Get("ETag"), path)
+	}
+
+	type account struct {
+		ID     int    `json:"id"`
+		Name   string `json:"name"`
+		Secret string `json:"secret"`
+	}
+	repository := middlewarex.NewMemoryRepository[account, int]()
+	assert.NoError(t, repository.Create(context.Background(), &account{Name: "a", Secret: "1234"}))
+	config := middlewarex.TypedCRUDConfig[account, int]{}
+	config.ETag = true
+	config.Fields = &middlewarex.FieldsConfig{Hidden: []string{"secret"}}
+	accounts := middlewarex.CRUDOfWithConfig(e.Group(""), "/accounts", repository, config)
+
+	rec = serve(e, http.MethodGet, "/accounts/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":1,"name":"a"}`, rec.Body.String())
+	sum := sha256.Sum256(rec.Body.Bytes())
+	assert.Equal(t, `"`+hex.EncodeToString(sum[:16])+`"`, rec.Header().Get("ETag"))
+
+	// The OpenAPI schemas do not publish the hidden fields
+	document := marshal(t, middlewarex.OpenAPI(middlewarex.OpenAPIConfig{}, accounts))
+	assert.NotContains(t, document, "secret")
+	assert.Contains(t, document, `"name":{"type":"string"}`)
+}
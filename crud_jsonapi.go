@@ -0,0 +1,309 @@
+package middlewarex
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+// MIMEApplicationJSONAPI is the media type of JSON:API documents.
+const MIMEApplicationJSONAPI = "application/vnd.api+json"
+
+const listResultContextKey = "middlewarex.crud.list_result"
+
+type (
+	// JSONAPIConfig defines the JSON:API (https://jsonapi.org) format of a typed CRUD resource (see TypedCRUDConfig.JSONAPI).
+	JSONAPIConfig[T any] struct {
+		// Type is the type of the resource objects (e.g. "books").
+		// Required.
+		Type string
+
+		// ID returns the ID of a value.
+		// Optional. Default value formats the ID field of T, named `ID` or tagged `crud:"id"`.
+		ID func(v T) string
+
+		// Relationships returns the relationships of a value.
+		// Optional.
+		Relationships func(c *echo.Context, v T) (map[string]JSONAPIRelationship, error)
+
+		// Include returns the resource objects of a compound document for the given `?include=' paths.
+		// Optional. Default value rejects the `?include=' query parameter with "400 - Bad Request" error.
+		Include func(c *echo.Context, paths []string, values []T) ([]JSONAPIResource, error)
+	}
+
+	// JSONAPIDocument is a JSON:API top-level document.
+	JSONAPIDocument struct {
+		Data     any               `json:"data,omitempty"`
+		Included []JSONAPIResource `json:"included,omitempty"`
+		Errors   []JSONAPIError    `json:"errors,omitempty"`
+		Links    map[string]string `json:"links,omitempty"`
+		Meta     map[string]any    `json:"meta,omitempty"`
+	}
+
+	// JSONAPIResource is a JSON:API resource object.
+	JSONAPIResource struct {
+		Type          string                         `json:"type"`
+		ID            string                         `json:"id,omitempty"`
+		Attributes    map[string]any                 `json:"attributes,omitempty"`
+		Relationships map[string]JSONAPIRelationship `json:"relationships,omitempty"`
+		Links         map[string]string              `json:"links,omitempty"`
+	}
+
+	// JSONAPIIdentifier is a JSON:API resource identifier object.
+	JSONAPIIdentifier struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	}
+
+	// JSONAPIRelationship is a JSON:API relationship object.
+	// Data is a JSONAPIIdentifier (to-one) or a []JSONAPIIdentifier (to-many), a nil *JSONAPIIdentifier renders an empty to-one relationship.
+	JSONAPIRelationship struct {
+		Data  any               `json:"data,omitempty"`
+		Links map[string]string `json:"links,omitempty"`
+	}
+
+	// JSONAPIError is a JSON:API error object.
+	JSONAPIError struct {
+		Status string `json:"status"`
+		Title  string `json:"title"`
+		Detail string `json:"detail,omitempty"`
+	}
+)
+
+// NewJSONAPIResource returns the resource object of the given value, its JSON fields being the attributes.
+// The `id' field is not an attribute.
+func NewJSONAPIResource(typ, id string, v any) (JSONAPIResource, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return JSONAPIResource{}, err
+	}
+
+	var attributes map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err = decoder.Decode(&attributes); err != nil {
+		return JSONAPIResource{}, fmt.Errorf("jsonapi: %s attributes must be an object: %w", typ, err)
+	}
+	delete(attributes, "id")
+
+	return JSONAPIResource{
+		Type:       typ,
+		ID:         id,
+		Attributes: attributes,
+	}, nil
+}
+
+// JSONAPIErrors is a middleware rendering the errors as JSON:API error objects.
+// The error is returned once rendered so it is still seen by the logging middlewares.
+func JSONAPIErrors(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		err := next(c)
+		if err == nil {
+			return nil
+		}
+		if r, _ := echo.UnwrapResponse(c.Response()); r != nil && r.Committed {
+			return err
+		}
+
+		code := echo.StatusCode(err)
+		if code == 0 {
+			code = http.StatusInternalServerError
+		}
+		object := JSONAPIError{
+			Status: strconv.Itoa(code),
+			Title:  http.StatusText(code),
+		}
+		var herr *echo.HTTPError
+		if errors.As(err, &herr) && herr.Message != object.Title {
+			object.Detail = herr.Message
+		}
+
+		if rerr := renderJSONAPI(c, code, JSONAPIDocument{Errors: []JSONAPIError{object}}); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+}
+
+// defaults sets the default values of the config.
+// It panics if the type is missing or if T has no ID field and no ID function is given.
+func (config JSONAPIConfig[T]) defaults() JSONAPIConfig[T] {
+	if config.Type == "" {
+		panic("JSON:API requires a type")
+	}
+	if config.ID != nil {
+		return config
+	}
+
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		panic("JSON:API requires an ID function for " + t.String())
+	}
+	var index []int
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		if tag := field.Tag.Get("crud"); tag == "id" || (tag == "" && field.Name == "ID" && index == nil) {
+			index = field.Index
+		}
+	}
+	if index == nil {
+		panic("JSON:API requires an ID field in " + t.String())
+	}
+
+	config.ID = func(v T) string {
+		return fmt.Sprint(reflect.ValueOf(v).FieldByIndex(index).Interface())
+	}
+	return config
+}
+
+// render renders v (a T or a []T) as a JSON:API document.
+// It handles the `?include=' compound documents, the `?fields[type]=' sparse fieldsets and the pagination links.
+func (config JSONAPIConfig[T]) render(c *echo.Context, code int, v any) error {
+	var values []T
+	switch v := v.(type) {
+	case T:
+		values = []T{v}
+	case []T:
+		values = v
+	default:
+		return fmt.Errorf("jsonapi: unsupported value %T", v)
+	}
+
+	fields := jsonAPIFields(c)
+	objects := make([]JSONAPIResource, len(values))
+	for i, value := range values {
+		object, err := NewJSONAPIResource(config.Type, config.ID(value), value)
+		if err != nil {
+			return err
+		}
+		if config.Relationships != nil {
+			if object.Relationships, err = config.Relationships(c, value); err != nil {
+				return err
+			}
+		}
+		objects[i] = sparseJSONAPIResource(object, fields)
+	}
+
+	document := JSONAPIDocument{
+		Links: map[string]string{"self": c.Request().URL.String()},
+	}
+	if _, ok := v.(T); ok {
+		document.Data = objects[0]
+	} else {
+		document.Data = objects
+		if q, ok := ListQueryFrom(c); ok {
+			result, _ := c.Get(listResultContextKey).(ListResult)
+			for _, link := range listLinks(c, q, result) {
+				document.Links[link.rel] = link.url
+			}
+			if result.Total >= 0 {
+				document.Meta = map[string]any{"total": result.Total}
+			}
+		}
+	}
+
+	if include := c.QueryParam("include"); include != "" {
+		if config.Include == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "include is not supported by "+config.Type)
+		}
+
+		included, err := config.Include(c, strings.Split(include, ","), values)
+		if err != nil {
+			return err
+		}
+		seen := map[JSONAPIIdentifier]bool{}
+		for _, object := range included {
+			id := JSONAPIIdentifier{Type: object.Type, ID: object.ID}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			document.Included = append(document.Included, sparseJSONAPIResource(object, fields))
+		}
+	}
+
+	return renderJSONAPI(c, code, document)
+}
+
+// bind binds the attributes of the request's JSON:API resource object into v.
+// It returns a "409 - Conflict" error when the resource object type is not the one of the resource.
+func (config JSONAPIConfig[T]) bind(c *echo.Context, v *T) error {
+	var document struct {
+		Data *struct {
+			Type       string          `json:"type"`
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&document); err != nil {
+		return echo.HTTPError{Code: http.StatusBadRequest, Message: "malformed JSON:API document"}.Wrap(err)
+	}
+	if document.Data == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing JSON:API primary data")
+	}
+	if document.Data.Type != config.Type {
+		return echo.NewHTTPError(http.StatusConflict, "resource object type must be "+config.Type)
+	}
+
+	if len(document.Data.Attributes) > 0 {
+		if err := json.Unmarshal(document.Data.Attributes, v); err != nil {
+			return echo.HTTPError{Code: http.StatusBadRequest, Message: "invalid attributes"}.Wrap(err)
+		}
+	}
+	return validateTyped(c, v)
+}
+
+// jsonAPIFields returns the sparse fieldsets of the request (`?fields[type]=a,b').
+func jsonAPIFields(c *echo.Context) map[string][]string {
+	fields := map[string][]string{}
+	for k, values := range c.QueryParams() {
+		typ, ok := strings.CutPrefix(k, "fields[")
+		if !ok || !strings.HasSuffix(typ, "]") || len(values) == 0 {
+			continue
+		}
+		fields[strings.TrimSuffix(typ, "]")] = strings.Split(values[0], ",")
+	}
+	return fields
+}
+
+// sparseJSONAPIResource returns the resource object restricted to the fieldset of its type.
+func sparseJSONAPIResource(object JSONAPIResource, fields map[string][]string) JSONAPIResource {
+	fieldset, ok := fields[object.Type]
+	if !ok {
+		return object
+	}
+
+	attributes := map[string]any{}
+	for k, v := range object.Attributes {
+		if slices.Contains(fieldset, k) {
+			attributes[k] = v
+		}
+	}
+	object.Attributes = attributes
+
+	relationships := map[string]JSONAPIRelationship{}
+	for k, v := range object.Relationships {
+		if slices.Contains(fieldset, k) {
+			relationships[k] = v
+		}
+	}
+	object.Relationships = relationships
+	return object
+}
+
+func renderJSONAPI(c *echo.Context, code int, document JSONAPIDocument) error {
+	payload, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return c.Blob(code, MIMEApplicationJSONAPI, payload)
+}
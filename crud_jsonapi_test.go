@@ -0,0 +1,114 @@
+package middlewarex_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCRUDOfJSONAPI(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUDOfWithConfig(e.Group(""), "/books", newBookRepository(book{ID: 1, Title: "Dune", Author: "Frank Herbert"}), middlewarex.TypedCRUDConfig[book, int]{
+		JSONAPI: &middlewarex.JSONAPIConfig[book]{
+			Type: "books",
+			Relationships: func(_ *echo.Context, v book) (map[string]middlewarex.JSONAPIRelationship, error) {
+				return map[string]middlewarex.JSONAPIRelationship{
+					"author": {Data: middlewarex.JSONAPIIdentifier{Type: "people", ID: v.Author}},
+				}, nil
+			},
+			Include: func(_ *echo.Context, paths []string, values []book) ([]middlewarex.JSONAPIResource, error) {
+				var included []middlewarex.JSONAPIResource
+				for _, v := range values {
+					person, err := middlewarex.NewJSONAPIResource("people", v.Author, map[string]any{"name": v.Author, "born": 1920})
+					if err != nil {
+						return nil, err
+					}
+					included = append(included, person, person)
+				}
+				return included, nil
+			},
+		},
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		code   int
+		exp    string
+	}{
+		{
+			method: http.MethodGet, path: "/books/1", code: http.StatusOK,
+			exp: `{"data":{"type":"books","id":"1","attributes":{"title":"Dune","author":"Frank Herbert"},` +
+				`"relationships":{"author":{"data":{"type":"people","id":"Frank Herbert"}}}},"links":{"self":"/books/1"}}`,
+		},
+		{
+			method: http.MethodGet, path: "/books?include=author&fields[books]=title&fields[people]=name", code: http.StatusOK,
+			exp: `{"data":[{"type":"books","id":"1","attributes":{"title":"Dune"}}],` +
+				`"included":[{"type":"people","id":"Frank Herbert","attributes":{"name":"Frank Herbert"}}],` +
+				`"links":{"self":"/books?include=author&fields[books]=title&fields[people]=name"}}`,
+		},
+		{
+			method: http.MethodPost, path: "/books", body: `{"data":{"type":"books","attributes":{"title":"Hyperion","author":"Dan Simmons"}}}`, code: http.StatusCreated,
+			exp: `{"data":{"type":"books","id":"2","attributes":{"title":"Hyperion","author":"Dan Simmons"},` +
+				`"relationships":{"author":{"data":{"type":"people","id":"Dan Simmons"}}}},"links":{"self":"/books"}}`,
+		},
+		{
+			method: http.MethodPatch, path: "/books/2", body: `{"data":{"type":"authors","id":"2","attributes":{"title":"Endymion"}}}`, code: http.StatusConflict,
+			exp: `{"errors":[{"status":"409","title":"Conflict","detail":"resource object type must be books"}]}`,
+		},
+		{
+			method: http.MethodGet, path: "/books/42", code: http.StatusNotFound,
+			exp: `{"errors":[{"status":"404","title":"Not Found"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		rec := serve(e, test.method, test.path, test.body)
+		info := test.method + " " + test.path
+		assert.Equal(t, test.code, rec.Code, info)
+		assert.Equal(t, middlewarex.MIMEApplicationJSONAPI, rec.Header().Get(echo.HeaderContentType), info)
+		assert.JSONEq(t, test.exp, rec.Body.String(), info)
+	}
+}
+
+func TestCRUDOfJSONAPIPagination(t *testing.T) {
+	repository := middlewarex.NewMemoryRepository[book, int]()
+	for i := range 5 {
+		assert.NoError(t, repository.Create(t.Context(), &book{Title: "Volume " + strconv.Itoa(i+1)}))
+	}
+
+	e := echo.New()
+	config := middlewarex.TypedCRUDConfig[book, int]{JSONAPI: &middlewarex.JSONAPIConfig[book]{Type: "books"}}
+	config.ListQuery = &middlewarex.ListQueryConfig{}
+	middlewarex.CRUDOfWithConfig(e.Group(""), "/books", repository, config)
+
+	rec := serve(e, http.MethodGet, "/books?page=2&per_page=2&fields[books]=title", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"data": [
+			{"type":"books","id":"3","attributes":{"title":"Volume 3"}},
+			{"type":"books","id":"4","attributes":{"title":"Volume 4"}}
+		],
+		"links": {
+			"self": "/books?page=2&per_page=2&fields[books]=title",
+			"first": "/books?fields%5Bbooks%5D=title&page=1&per_page=2",
+			"prev": "/books?fields%5Bbooks%5D=title&page=1&per_page=2",
+			"next": "/books?fields%5Bbooks%5D=title&page=3&per_page=2",
+			"last": "/books?fields%5Bbooks%5D=title&page=3&per_page=2"
+		},
+		"meta": {"total": 5}
+	}`, rec.Body.String())
+
+	rec = serve(e, http.MethodGet, "/books?include=author", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"errors":[{"status":"400","title":"Bad Request","detail":"include is not supported by books"}]}`, rec.Body.String())
+
+	rec = serve(e, http.MethodGet, "/books?per_page=-1", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, middlewarex.MIMEApplicationJSONAPI, rec.Header().Get(echo.HeaderContentType))
+}
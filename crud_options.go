@@ -0,0 +1,157 @@
+package middlewarex
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+// CORSConfig defines the CORS handling of CRUD resources.
+// The allowed methods of a path are the ones of the registered verbs and actions.
+type CORSConfig struct {
+	// AllowOrigins is the list of origins allowed to access the resource.
+	// Optional. Default value ["*"].
+	AllowOrigins []string
+
+	// AllowHeaders is the list of request headers allowed in preflight requests.
+	// Optional. Default value allows the requested headers.
+	AllowHeaders []string
+
+	// ExposeHeaders is the list of response headers exposed to the clients (e.g. X-Total-Count).
+	// Optional.
+	ExposeHeaders []string
+
+	// AllowCredentials allows the requests with credentials (e.g. cookies).
+	// CRUD panics when combined with the "*" origin, unless UnsafeWildcardOriginWithAllowCredentials is set.
+	// Optional.
+	AllowCredentials bool
+
+	// UnsafeWildcardOriginWithAllowCredentials allows the "*" origin with AllowCredentials,
+	// the request origin is then reflected with credentials, letting any website make authenticated requests.
+	// Optional.
+	UnsafeWildcardOriginWithAllowCredentials bool
+
+	// MaxAge is the number of seconds the result of a preflight request can be cached.
+	// Optional. Default value does not set the header.
+	MaxAge int
+}
+
+// DefaultCORSConfig is the default CORS config.
+var DefaultCORSConfig = CORSConfig{
+	AllowOrigins: []string{"*"},
+}
+
+var methodsOrder = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// fallback returns the handler of the methods not registered on the given path.
+// OPTIONS requests get a "204 - No Content" with the Allow header (and the CORS preflight headers),
+// other methods a "405 - Method Not Allowed" with the Allow header.
+func (r *Resource) fallback(path string) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		methods := r.methods[path]
+		h := c.Response().Header()
+		h.Set(echo.HeaderAllow, allowHeader(methods))
+
+		if c.Request().Method != http.MethodOptions {
+			return echo.ErrMethodNotAllowed
+		}
+
+		if r.config.CORS != nil {
+			preflight(c, *r.config.CORS, methods)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// allowHeader returns the value of the Allow header of the given methods.
+func allowHeader(methods []string) string {
+	methods = append([]string{http.MethodOptions}, methods...)
+	slices.SortStableFunc(methods, func(a, b string) int {
+		return methodIndex(a) - methodIndex(b)
+	})
+	return strings.Join(slices.Compact(methods), ", ")
+}
+
+func methodIndex(method string) int {
+	if method == http.MethodOptions {
+		return -1
+	}
+	if i := slices.Index(methodsOrder, method); i >= 0 {
+		return i
+	}
+	return len(methodsOrder)
+}
+
+// preflight sets the CORS headers of a preflight request.
+// No headers are set when the origin or the requested method is not allowed.
+func preflight(c *echo.Context, config CORSConfig, methods []string) {
+	req := c.Request()
+	h := c.Response().Header()
+	h.Add(echo.HeaderVary, echo.HeaderOrigin)
+	h.Add(echo.HeaderVary, echo.HeaderAccessControlRequestMethod)
+	h.Add(echo.HeaderVary, echo.HeaderAccessControlRequestHeaders)
+
+	origin, ok := allowOrigin(config, req.Header.Get(echo.HeaderOrigin))
+	if !ok || !slices.Contains(methods, req.Header.Get(echo.HeaderAccessControlRequestMethod)) {
+		return
+	}
+
+	h.Set(echo.HeaderAccessControlAllowOrigin, origin)
+	h.Set(echo.HeaderAccessControlAllowMethods, strings.Join(methods, ", "))
+	if len(config.AllowHeaders) > 0 {
+		h.Set(echo.HeaderAccessControlAllowHeaders, strings.Join(config.AllowHeaders, ", "))
+	} else if headers := req.Header.Get(echo.HeaderAccessControlRequestHeaders); headers != "" {
+		h.Set(echo.HeaderAccessControlAllowHeaders, headers)
+	}
+	if config.AllowCredentials {
+		h.Set(echo.HeaderAccessControlAllowCredentials, "true")
+	}
+	if config.MaxAge > 0 {
+		h.Set(echo.HeaderAccessControlMaxAge, strconv.Itoa(config.MaxAge))
+	}
+}
+
+// withCORS returns a middleware setting the CORS headers of the actual (non-preflight) requests.
+func withCORS(config CORSConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			h := c.Response().Header()
+			h.Add(echo.HeaderVary, echo.HeaderOrigin)
+
+			if origin, ok := allowOrigin(config, c.Request().Header.Get(echo.HeaderOrigin)); ok {
+				h.Set(echo.HeaderAccessControlAllowOrigin, origin)
+				if config.AllowCredentials {
+					h.Set(echo.HeaderAccessControlAllowCredentials, "true")
+				}
+				if len(config.ExposeHeaders) > 0 {
+					h.Set(echo.HeaderAccessControlExposeHeaders, strings.Join(config.ExposeHeaders, ", "))
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value of the given request origin.
+func allowOrigin(config CORSConfig, origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+	if slices.Contains(config.AllowOrigins, "*") {
+		if config.AllowCredentials && config.UnsafeWildcardOriginWithAllowCredentials {
+			return origin, true // The wildcard is not allowed with credentials
+		}
+		return "*", true
+	}
+	return origin, slices.Contains(config.AllowOrigins, origin)
+}
@@ -0,0 +1,102 @@
+package middlewarex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCRUDMethodNotAllowed(t *testing.T) {
+	e := echo.New()
+	orders := middlewarex.CRUD(e.Group(""), "/orders", &crud2ctrl{})
+	orders.Action(middlewarex.Action{Name: "cancel", Member: true, Handler: func(c *echo.Context) error { return c.NoContent(http.StatusOK) }})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		{method: http.MethodPost, path: "/orders", code: http.StatusMethodNotAllowed, allow: "OPTIONS, GET"},
+		{method: http.MethodOptions, path: "/orders", code: http.StatusNoContent, allow: "OPTIONS, GET"},
+		{method: http.MethodPut, path: "/orders/1", code: http.StatusMethodNotAllowed, allow: "OPTIONS, GET, DELETE"},
+		{method: http.MethodOptions, path: "/orders/1", code: http.StatusNoContent, allow: "OPTIONS, GET, DELETE"},
+		{method: http.MethodGet, path: "/orders/1/cancel", code: http.StatusMethodNotAllowed, allow: "OPTIONS, POST"},
+		{method: http.MethodGet, path: "/orders/1", code: http.StatusOK},
+	}
+	for _, test := range tests {
+		rec := serve(e, test.method, test.path, "")
+		assert.Equal(t, test.code, rec.Code, test.method+" "+test.path)
+		assert.Equal(t, test.allow, rec.Header().Get(echo.HeaderAllow), test.method+" "+test.path)
+	}
+}
+
+func TestCRUDCORS(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUDWithConfig(e.Group(""), "/orders", &crud2ctrl{}, middlewarex.CRUDConfig{
+		CORS: &middlewarex.CORSConfig{
+			AllowOrigins:  []string{"https://example.com"},
+			ExposeHeaders: []string{middlewarex.XTotalCount},
+			MaxAge:        600,
+		},
+	})
+
+	preflight := func(origin, method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/orders/1", nil)
+		req.Header.Set(echo.HeaderOrigin, origin)
+		req.Header.Set(echo.HeaderAccessControlRequestMethod, method)
+		req.Header.Set(echo.HeaderAccessControlRequestHeaders, "Authorization")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := preflight("https://example.com", http.MethodDelete)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "GET, DELETE", rec.Header().Get(echo.HeaderAccessControlAllowMethods))
+	assert.Equal(t, "Authorization", rec.Header().Get(echo.HeaderAccessControlAllowHeaders))
+	assert.Equal(t, "600", rec.Header().Get(echo.HeaderAccessControlMaxAge))
+
+	rec = preflight("https://example.com", http.MethodPatch)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	rec = preflight("https://evil.com", http.MethodDelete)
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://example.com")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, middlewarex.XTotalCount, rec.Header().Get(echo.HeaderAccessControlExposeHeaders))
+}
+
+func TestCRUDCORSWildcardCredentials(t *testing.T) {
+	assert.PanicsWithValue(t, "CORS wildcard origin with credentials requires UnsafeWildcardOriginWithAllowCredentials for /orders", func() {
+		middlewarex.CRUDWithConfig(echo.New().Group(""), "/orders", &crud2ctrl{}, middlewarex.CRUDConfig{
+			CORS: &middlewarex.CORSConfig{AllowCredentials: true},
+		})
+	})
+
+	e := echo.New()
+	middlewarex.CRUDWithConfig(e.Group(""), "/orders", &crud2ctrl{}, middlewarex.CRUDConfig{
+		CORS: &middlewarex.CORSConfig{
+			AllowCredentials:                         true,
+			UnsafeWildcardOriginWithAllowCredentials: true,
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://example.com")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "https://example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "true", rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+}
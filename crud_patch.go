@@ -0,0 +1,421 @@
+package middlewarex
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+const (
+	// MIMEApplicationMergePatch is the media type of JSON Merge Patch (RFC 7396) documents.
+	MIMEApplicationMergePatch = "application/merge-patch+json"
+	// MIMEApplicationJSONPatch is the media type of JSON Patch (RFC 6902) documents.
+	MIMEApplicationJSONPatch = "application/json-patch+json"
+
+	headerAcceptPatch = "Accept-Patch"
+)
+
+// ErrInvalidPatch is returned when a patch is malformed or cannot be applied.
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// IsPatch returns true when the request's body is a JSON Merge Patch or a JSON Patch.
+func IsPatch(c *echo.Context) bool {
+	switch patchMediaType(c) {
+	case MIMEApplicationMergePatch, MIMEApplicationJSONPatch:
+		return true
+	}
+	return false
+}
+
+// PatchJSON applies the request's patch to the given JSON document and returns the patched document.
+// The patch format is chosen by the Content-Type header: JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902).
+// It returns a "415 - Unsupported Media Type" error for other media types
+// and a "422 - Unprocessable Entity" error for invalid patches.
+// The JSON Patch operations referencing the hidden fields of the CRUD resource (see FieldsConfig.Hidden) are invalid.
+func PatchJSON(c *echo.Context, document []byte) ([]byte, error) {
+	apply := MergePatch
+	switch patchMediaType(c) {
+	case MIMEApplicationMergePatch:
+	case MIMEApplicationJSONPatch:
+		hidden, _ := c.Get(hiddenFieldsContextKey).(fieldTree)
+		apply = func(document, patch []byte) ([]byte, error) {
+			return jsonPatch(document, patch, hidden)
+		}
+	default:
+		c.Response().Header().Set(headerAcceptPatch, MIMEApplicationMergePatch+", "+MIMEApplicationJSONPatch)
+		return nil, echo.ErrUnsupportedMediaType
+	}
+
+	patch, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := apply(document, patch)
+	if errors.Is(err, ErrInvalidPatch) {
+		return nil, echo.HTTPError{Code: http.StatusUnprocessableEntity, Message: err.Error()}.Wrap(err)
+	}
+	return patched, err
+}
+
+// MergePatch applies the JSON Merge Patch (RFC 7396) to the given JSON document.
+// Patch errors wrap ErrInvalidPatch.
+func MergePatch(document, patch []byte) ([]byte, error) {
+	target, err := decodePatchJSON(document)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decodePatchJSON(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+// JSONPatch applies the JSON Patch (RFC 6902) to the given JSON document.
+// The operations are applied in order and the whole patch fails if one of them fails.
+// Patch errors wrap ErrInvalidPatch.
+func JSONPatch(document, patch []byte) ([]byte, error) {
+	return jsonPatch(document, patch, nil)
+}
+
+// jsonPatch applies the JSON Patch, the operations referencing the hidden fields are invalid.
+// Reading a parent of a hidden field (`from' and `test' paths) is invalid too.
+func jsonPatch(document, patch []byte, hidden fieldTree) ([]byte, error) {
+	target, err := decodePatchJSON(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []struct {
+		Op    string          `json:"op"`
+		Path  *string         `json:"path"`
+		From  *string         `json:"from"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err = json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: JSON Patch must be an array of operations", ErrInvalidPatch)
+	}
+
+	for i, operation := range operations {
+		fail := func(format string, a ...any) error {
+			return fmt.Errorf("%w: operation %d (%s): %s", ErrInvalidPatch, i, operation.Op, fmt.Sprintf(format, a...))
+		}
+
+		if operation.Path == nil {
+			return nil, fail("missing path")
+		}
+		path, err := parseJSONPointer(*operation.Path)
+		if err != nil {
+			return nil, fail("%s", err)
+		}
+		if within, parent := hidden.pointer(path); within || (parent && operation.Op == "test") {
+			return nil, fail("path %q is not allowed", *operation.Path)
+		}
+
+		var value any
+		switch operation.Op {
+		case "add", "replace", "test":
+			if operation.Value == nil {
+				return nil, fail("missing value")
+			}
+			if value, err = decodePatchJSON(operation.Value); err != nil {
+				return nil, fail("%s", err)
+			}
+		case "move", "copy":
+			if operation.From == nil {
+				return nil, fail("missing from")
+			}
+			from, err := parseJSONPointer(*operation.From)
+			if err != nil {
+				return nil, fail("%s", err)
+			}
+			if within, parent := hidden.pointer(from); within || parent {
+				return nil, fail("from %q is not allowed", *operation.From)
+			}
+			if operation.Op == "move" {
+				if isPointerPrefix(from, path) && len(from) < len(path) {
+					return nil, fail("cannot move a value into one of its children")
+				}
+				if target, value, err = jsonPointerRemove(target, from); err != nil {
+					return nil, fail("%s", err)
+				}
+			} else {
+				if value, err = jsonPointerGet(target, from); err != nil {
+					return nil, fail("%s", err)
+				}
+				value = copyJSON(value)
+			}
+		}
+
+		switch operation.Op {
+		case "add", "move", "copy":
+			target, err = jsonPointerAdd(target, path, value)
+		case "remove":
+			target, _, err = jsonPointerRemove(target, path)
+		case "replace":
+			if target, _, err = jsonPointerRemove(target, path); err == nil || len(path) == 0 {
+				target, err = jsonPointerAdd(target, path, value)
+			}
+		case "test":
+			var current any
+			if current, err = jsonPointerGet(target, path); err == nil && !equalJSON(current, value) {
+				err = errors.New("test failed")
+			}
+		default:
+			err = errors.New("unknown operation")
+		}
+		if err != nil {
+			return nil, fail("%s", err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func patchMediaType(c *echo.Context) string {
+	mediatype, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	return mediatype
+}
+
+func decodePatchJSON(data []byte) (any, error) {
+	var v any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return v, nil
+}
+
+// parseJSONPointer parses a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPointerPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex parses an array index token, last is the greatest allowed index.
+func arrayIndex(token string, last int) (int, error) {
+	if token == "-" {
+		token = strconv.Itoa(last)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > last || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return i, nil
+}
+
+func jsonPointerGet(node any, path []string) (any, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			v, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("missing member %q", token)
+			}
+			node = v
+		case []any:
+			if token == "-" {
+				return nil, fmt.Errorf("invalid array index %q", token)
+			}
+			i, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("cannot reference %q in a scalar value", token)
+		}
+	}
+	return node, nil
+}
+
+// jsonPointerAdd returns the node with the value added at path.
+func jsonPointerAdd(node any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token := path[0]
+	switch n := node.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			n[token] = value
+			return n, nil
+		}
+		child, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("missing member %q", token)
+		}
+		child, err := jsonPointerAdd(child, path[1:], value)
+		n[token] = child
+		return n, err
+	case []any:
+		if len(path) == 1 {
+			i, err := arrayIndex(token, len(n))
+			if err != nil {
+				return nil, err
+			}
+			n = append(n[:i], append([]any{value}, n[i:]...)...)
+			return n, nil
+		}
+		i, err := arrayIndex(token, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		n[i], err = jsonPointerAdd(n[i], path[1:], value)
+		return n, err
+	default:
+		return nil, fmt.Errorf("cannot reference %q in a scalar value", token)
+	}
+}
+
+// jsonPointerRemove returns the node without the value at path and the removed value.
+func jsonPointerRemove(node any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+
+	token := path[0]
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("missing member %q", token)
+		}
+		if len(path) == 1 {
+			delete(n, token)
+			return n, child, nil
+		}
+		child, removed, err := jsonPointerRemove(child, path[1:])
+		n[token] = child
+		return n, removed, err
+	case []any:
+		if token == "-" {
+			return nil, nil, fmt.Errorf("invalid array index %q", token)
+		}
+		i, err := arrayIndex(token, len(n)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(path) == 1 {
+			removed := n[i]
+			return append(n[:i], n[i+1:]...), removed, nil
+		}
+		var removed any
+		n[i], removed, err = jsonPointerRemove(n[i], path[1:])
+		return n, removed, err
+	default:
+		return nil, nil, fmt.Errorf("cannot reference %q in a scalar value", token)
+	}
+}
+
+func copyJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = copyJSON(e)
+		}
+		return m
+	case []any:
+		a := make([]any, len(v))
+		for i, e := range v {
+			a[i] = copyJSON(e)
+		}
+		return a
+	}
+	return v
+}
+
+// equalJSON compares JSON values, numbers are compared by value.
+func equalJSON(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, ok := b[k]; !ok || !equalJSON(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalJSON(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, errx := a.Float64()
+		y, erry := b.Float64()
+		return errx == nil && erry == nil && x == y
+	}
+	return a == b
+}
@@ -0,0 +1,171 @@
+package middlewarex_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		document string
+		patch    string
+		exp      string
+	}{
+		{document: `{"a":"b"}`, patch: `{"a":"c"}`, exp: `{"a":"c"}`},
+		{document: `{"a":"b"}`, patch: `{"b":"c"}`, exp: `{"a":"b","b":"c"}`},
+		{document: `{"a":"b"}`, patch: `{"a":null}`, exp: `{}`},
+		{document: `{"a":"b","b":"c"}`, patch: `{"a":null}`, exp: `{"b":"c"}`},
+		{document: `{"a":["b"]}`, patch: `{"a":"c"}`, exp: `{"a":"c"}`},
+		{document: `{"a":"c"}`, patch: `{"a":["b"]}`, exp: `{"a":["b"]}`},
+		{document: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, exp: `{"a":{"b":"d"}}`},
+		{document: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, exp: `{"a":[1]}`},
+		{document: `["a","b"]`, patch: `["c","d"]`, exp: `["c","d"]`},
+		{document: `{"a":"b"}`, patch: `["c"]`, exp: `["c"]`},
+		{document: `{"e":null}`, patch: `{"a":1}`, exp: `{"e":null,"a":1}`},
+		{document: `[1,2]`, patch: `{"a":"b","c":null}`, exp: `{"a":"b"}`},
+		{document: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, exp: `{"a":{"bb":{}}}`},
+	}
+
+	for _, test := range tests {
+		patched, err := middlewarex.MergePatch([]byte(test.document), []byte(test.patch))
+		assert.NoError(t, err, test.patch)
+		assert.JSONEq(t, test.exp, string(patched), test.patch)
+	}
+
+	_, err := middlewarex.MergePatch([]byte(`{}`), []byte(`{"a":`))
+	assert.ErrorIs(t, err, middlewarex.ErrInvalidPatch)
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		document string
+		patch    string
+		exp      string
+	}{
+		{document: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux"}]`, exp: `{"baz":"qux","foo":"bar"}`},
+		{document: `{"foo":["bar","baz"]}`, patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, exp: `{"foo":["bar","qux","baz"]}`},
+		{document: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, exp: `{"foo":["bar",["abc","def"]]}`},
+		{document: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, exp: `{"foo":"bar"}`},
+		{document: `{"foo":["bar","qux","baz"]}`, patch: `[{"op":"remove","path":"/foo/1"}]`, exp: `{"foo":["bar","baz"]}`},
+		{document: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":"boo"}]`, exp: `{"baz":"boo","foo":"bar"}`},
+		{
+			document: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch:    `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			exp:      `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{document: `{"foo":["all","grass","cows","eat"]}`, patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, exp: `{"foo":["all","cows","eat","grass"]}`},
+		{document: `{"foo":{"bar":[1]}}`, patch: `[{"op":"copy","from":"/foo/bar","path":"/baz"},{"op":"add","path":"/baz/-","value":2}]`, exp: `{"foo":{"bar":[1]},"baz":[1,2]}`},
+		{document: `{"baz":"qux","foo":["a",2,"c"]}`, patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, exp: `{"baz":"qux","foo":["a",2,"c"]}`},
+		{document: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, exp: `{"foo":"bar","child":{"grandchild":{}}}`},
+		{document: `{"/":0,"~":1}`, patch: `[{"op":"replace","path":"/~1","value":2},{"op":"remove","path":"/~0"}]`, exp: `{"/":2}`},
+		{document: `{"foo":"bar"}`, patch: `[{"op":"replace","path":"","value":[1]}]`, exp: `[1]`},
+	}
+
+	for _, test := range tests {
+		patched, err := middlewarex.JSONPatch([]byte(test.document), []byte(test.patch))
+		assert.NoError(t, err, test.patch)
+		assert.JSONEq(t, test.exp, string(patched), test.patch)
+	}
+
+	invalids := []string{
+		`{"op":"add","path":"/a","value":1}`,
+		`[{"op":"add","path":"/a"}]`,
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"replace","path":"/missing","value":1}]`,
+		`[{"op":"add","path":"/foo/bar","value":1}]`,
+		`[{"op":"add","path":"/list/3","value":1}]`,
+		`[{"op":"add","path":"/list/01","value":1}]`,
+		`[{"op":"test","path":"/foo","value":"baz"}]`,
+		`[{"op":"move","from":"/list","path":"/list/0"}]`,
+		`[{"op":"copy","path":"/a"}]`,
+		`[{"op":"invalid","path":"/a"}]`,
+		`[{"op":"add","path":"a","value":1}]`,
+	}
+	for _, patch := range invalids {
+		_, err := middlewarex.JSONPatch([]byte(`{"foo":"bar","list":[1]}`), []byte(patch))
+		assert.ErrorIs(t, err, middlewarex.ErrInvalidPatch, patch)
+	}
+}
+
+func TestCRUDOfPatch(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUDOf(e.Group(""), "/books", newBookRepository(book{ID: 1, Title: "Dune", Author: "Frank Herbert"}))
+
+	tests := []struct {
+		mediatype string
+		body      string
+		code      int
+		exp       string
+	}{
+		{mediatype: middlewarex.MIMEApplicationMergePatch, body: `{"author":null,"title":"Dune Messiah"}`, code: http.StatusOK, exp: `{"id":1,"title":"Dune Messiah","author":""}`},
+		{mediatype: middlewarex.MIMEApplicationJSONPatch, body: `[{"op":"test","path":"/title","value":"Dune Messiah"},{"op":"add","path":"/author","value":"Frank Herbert"}]`, code: http.StatusOK, exp: `{"id":1,"title":"Dune Messiah","author":"Frank Herbert"}`},
+		{mediatype: middlewarex.MIMEApplicationJSONPatch + "; charset=utf-8", body: `[{"op":"test","path":"/title","value":"Dune"}]`, code: http.StatusUnprocessableEntity},
+		{mediatype: middlewarex.MIMEApplicationJSONPatch, body: `[{"op":"replace","path":"/title","value":42}]`, code: http.StatusUnprocessableEntity},
+		{mediatype: middlewarex.MIMEApplicationMergePatch, body: `{"title":`, code: http.StatusUnprocessableEntity},
+		{mediatype: echo.MIMEApplicationJSON, body: `{"title":"Children of Dune"}`, code: http.StatusOK, exp: `{"id":1,"title":"Children of Dune","author":"Frank Herbert"}`},
+		{mediatype: echo.MIMETextPlain, body: `title=Dune`, code: http.StatusUnsupportedMediaType},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPatch, "/books/1", strings.NewReader(test.body))
+		req.Header.Set(echo.HeaderContentType, test.mediatype)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, test.code, rec.Code, test.body)
+		if test.exp != "" {
+			assert.JSONEq(t, test.exp, rec.Body.String(), test.body)
+		}
+	}
+}
+
+func TestCRUDOfPatchHiddenFields(t *testing.T) {
+	type account struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		Internal string `json:"-"`
+	}
+
+	repository := middlewarex.NewMemoryRepository[account, int]()
+	assert.NoError(t, repository.Create(context.Background(), &account{Name: "a", Internal: "secret"}))
+	e := echo.New()
+	middlewarex.CRUDOf(e.Group(""), "/accounts", repository)
+
+	for _, mediatype := range []string{middlewarex.MIMEApplicationMergePatch, middlewarex.MIMEApplicationJSONPatch} {
+		body := `{"name":"b"}`
+		if mediatype == middlewarex.MIMEApplicationJSONPatch {
+			body = `[{"op":"replace","path":"/name","value":"c"}]`
+		}
+		req := httptest.NewRequest(http.MethodPatch, "/accounts/1", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, mediatype)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, mediatype)
+
+		v, err := repository.Get(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", v.Internal, mediatype)
+	}
+
+	v, _ := repository.Get(context.Background(), 1)
+	assert.Equal(t, "c", v.Name)
+}
+
+func TestPatchJSON(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"a":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	_, err := middlewarex.PatchJSON(c, []byte(`{}`))
+	assert.Equal(t, http.StatusUnsupportedMediaType, echo.StatusCode(err))
+	assert.Equal(t, middlewarex.MIMEApplicationMergePatch+", "+middlewarex.MIMEApplicationJSONPatch, rec.Header().Get("Accept-Patch"))
+}
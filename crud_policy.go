@@ -0,0 +1,215 @@
+package middlewarex
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+)
+
+// PolicySupported interface
+// It authorizes the CRUD actions of the token's owner, CRUD enforces it before running the actions:
+//   - CanList: List
+//   - CanCreate: Create and BulkCreate
+//   - CanShow: Show
+//   - CanUpdate: Update, Replace and each item of BulkUpdate (identified by its member named like CRUDConfig.IDParam)
+//   - CanDelete: Delete, SoftDelete, Restore, each ID of BulkDelete and Purge (see PurgePolicySupported)
+//
+// The ID is empty for singular resources (see CRUDConfig.Singular).
+// Denied requests get a "403 - Forbidden" error (see CRUDPolicyConfig.NotFound), denied bulk items a failed BulkResult.
+// Custom actions are not covered, use CRUDConfig.Middlewares.
+type PolicySupported interface {
+	CanList(c *echo.Context, token Token) (bool, error)
+	CanCreate(c *echo.Context, token Token) (bool, error)
+	CanShow(c *echo.Context, token Token, id string) (bool, error)
+	CanUpdate(c *echo.Context, token Token, id string) (bool, error)
+	CanDelete(c *echo.Context, token Token, id string) (bool, error)
+}
+
+// PurgePolicySupported interface
+// It authorizes the Purge action of the token's owner in place of CanDelete.
+type PurgePolicySupported interface {
+	CanPurge(c *echo.Context, token Token, id string) (bool, error)
+}
+
+// CRUDPolicyConfig defines the enforcement of the PolicySupported resources.
+type CRUDPolicyConfig struct {
+	// ContextKey is the context key where the PASETO middleware stored the token.
+	// Requests without token get a "401 - Unauthorized" error.
+	// Optional. Default value "paseto".
+	ContextKey string
+
+	// NotFound answers "404 - Not Found" instead of "403 - Forbidden" to the denied member requests,
+	// hiding the existence of the values.
+	// Optional.
+	NotFound bool
+}
+
+// DefaultCRUDPolicyConfig is the default CRUDPolicyConfig.
+var DefaultCRUDPolicyConfig = CRUDPolicyConfig{
+	ContextKey: DefaultPASETOConfig.ContextKey,
+}
+
+// crudPolicy enforces the policy of a resource.
+type crudPolicy struct {
+	policy  PolicySupported
+	purge   PurgePolicySupported
+	config  CRUDPolicyConfig
+	idParam string
+}
+
+// middleware returns the middleware authorizing the given verb.
+func (p *crudPolicy) middleware(verb Verb) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			token, ok := contextToken(c, p.config.ContextKey)
+			if !ok {
+				return ErrPolicyUnauthenticated
+			}
+
+			id := c.Param(p.idParam)
+			var allowed bool
+			var err error
+			switch verb {
+			case VerbList:
+				allowed, err = p.policy.CanList(c, token)
+			case VerbCreate, VerbBulkCreate:
+				allowed, err = p.policy.CanCreate(c, token)
+			case VerbShow:
+				allowed, err = p.policy.CanShow(c, token, id)
+			case VerbUpdate, VerbReplace:
+				allowed, err = p.policy.CanUpdate(c, token, id)
+			case VerbPurge:
+				if p.purge == nil {
+					allowed, err = p.policy.CanDelete(c, token, id)
+					break
+				}
+				allowed, err = p.purge.CanPurge(c, token, id)
+			default:
+				allowed, err = p.policy.CanDelete(c, token, id)
+			}
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				return p.denied(verb&(VerbList|VerbCreate|VerbBulkCreate) == 0)
+			}
+			return next(c)
+		}
+	}
+}
+
+func (p *crudPolicy) denied(member bool) error {
+	if member && p.config.NotFound {
+		return echo.ErrNotFound
+	}
+	return echo.ErrForbidden
+}
+
+// bulkUpdate returns a BulkUpdate handler authorizing each item with CanUpdate.
+func (p *crudPolicy) bulkUpdate(handler func(*echo.Context, []json.RawMessage) ([]BulkResult, error)) func(*echo.Context, []json.RawMessage) ([]BulkResult, error) {
+	return func(c *echo.Context, items []json.RawMessage) ([]BulkResult, error) {
+		ids := make([]string, len(items))
+		for i, item := range items {
+			ids[i] = bulkItemID(item, p.idParam)
+		}
+
+		return p.bulk(c, ids, p.policy.CanUpdate, func(allowed []int) ([]BulkResult, error) {
+			selected := make([]json.RawMessage, len(allowed))
+			for i, j := range allowed {
+				selected[i] = items[j]
+			}
+			return handler(c, selected)
+		})
+	}
+}
+
+// bulkItemID returns the ID of the bulk item from its member named like the ID path parameter.
+// Numeric IDs are canonicalized (e.g. 4.2e1 is "42") so the policy authorizes the ID the handler decodes.
+func bulkItemID(item json.RawMessage, member string) string {
+	var v map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(item))
+	decoder.UseNumber()
+	if decoder.Decode(&v) != nil {
+		return ""
+	}
+
+	switch id := v[member].(type) {
+	case string:
+		return id
+	case json.Number:
+		n, ok := new(big.Rat).SetString(id.String())
+		if !ok {
+			return id.String()
+		}
+		if n.IsInt() {
+			return n.Num().String()
+		}
+		f, _ := n.Float64()
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return ""
+}
+
+// bulkDelete returns a BulkDelete handler authorizing each ID with CanDelete.
+func (p *crudPolicy) bulkDelete(handler func(*echo.Context, []string) ([]BulkResult, error)) func(*echo.Context, []string) ([]BulkResult, error) {
+	return func(c *echo.Context, ids []string) ([]BulkResult, error) {
+		return p.bulk(c, ids, p.policy.CanDelete, func(allowed []int) ([]BulkResult, error) {
+			selected := make([]string, len(allowed))
+			for i, j := range allowed {
+				selected[i] = ids[j]
+			}
+			return handler(c, selected)
+		})
+	}
+}
+
+// bulk authorizes each ID and calls the handler with the indexes of the allowed items.
+// The denied items get a failed BulkResult at their position.
+func (p *crudPolicy) bulk(c *echo.Context, ids []string, can func(*echo.Context, Token, string) (bool, error), handler func(allowed []int) ([]BulkResult, error)) ([]BulkResult, error) {
+	token, ok := contextToken(c, p.config.ContextKey)
+	if !ok {
+		return nil, ErrPolicyUnauthenticated
+	}
+
+	denied := map[int]BulkResult{}
+	var allowed []int
+	for i, id := range ids {
+		if id == "" {
+			denied[i] = BulkError(id, echo.NewHTTPError(http.StatusBadRequest, "missing id of item "+strconv.Itoa(i)))
+			continue
+		}
+
+		ok, err := can(c, token, id)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			denied[i] = BulkError(id, p.denied(true))
+			continue
+		}
+		allowed = append(allowed, i)
+	}
+
+	var results []BulkResult
+	if len(allowed) > 0 {
+		var err error
+		if results, err = handler(allowed); err != nil {
+			return nil, err
+		}
+	}
+
+	merged := make([]BulkResult, 0, len(ids))
+	for i := range ids {
+		if result, ok := denied[i]; ok {
+			merged = append(merged, result)
+		} else if len(results) > 0 {
+			merged = append(merged, results[0])
+			results = results[1:]
+		}
+	}
+	return append(merged, results...), nil
+}
@@ -0,0 +1,204 @@
+package middlewarex_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/o1egl/paseto/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// crudPolicyCtrl allows the members to their owner and the collection to everyone but "eve".
+type crudPolicyCtrl struct {
+	owners map[string]string
+}
+
+func (*crudPolicyCtrl) Create(c *echo.Context) error { return c.NoContent(http.StatusCreated) }
+func (*crudPolicyCtrl) List(c *echo.Context) error   { return c.NoContent(http.StatusOK) }
+func (*crudPolicyCtrl) Show(c *echo.Context) error   { return c.NoContent(http.StatusOK) }
+func (*crudPolicyCtrl) Update(c *echo.Context) error { return c.NoContent(http.StatusOK) }
+func (*crudPolicyCtrl) Delete(c *echo.Context) error { return c.NoContent(http.StatusNoContent) }
+
+func (*crudPolicyCtrl) BulkDelete(_ *echo.Context, ids []string) ([]middlewarex.BulkResult, error) {
+	results := make([]middlewarex.BulkResult, len(ids))
+	for i, id := range ids {
+		results[i] = middlewarex.BulkResult{ID: id, Status: http.StatusNoContent}
+	}
+	return results, nil
+}
+
+func (*crudPolicyCtrl) CanList(_ *echo.Context, token middlewarex.Token) (bool, error) {
+	return token.Subject != "eve", nil
+}
+
+func (*crudPolicyCtrl) CanCreate(_ *echo.Context, token middlewarex.Token) (bool, error) {
+	return token.Subject != "eve", nil
+}
+
+func (ctrl *crudPolicyCtrl) CanShow(_ *echo.Context, token middlewarex.Token, id string) (bool, error) {
+	return ctrl.owners[id] == token.Subject, nil
+}
+
+func (ctrl *crudPolicyCtrl) CanUpdate(_ *echo.Context, token middlewarex.Token, id string) (bool, error) {
+	return ctrl.owners[id] == token.Subject, nil
+}
+
+func (ctrl *crudPolicyCtrl) CanDelete(_ *echo.Context, token middlewarex.Token, id string) (bool, error) {
+	return ctrl.owners[id] == token.Subject, nil
+}
+
+func withSubject(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		if subject := c.Request().Header.Get("X-Subject"); subject != "" {
+			c.Set("paseto", middlewarex.Token{JSONToken: paseto.JSONToken{Subject: subject}})
+		}
+		return next(c)
+	}
+}
+
+func TestCRUDPolicy(t *testing.T) {
+	ctrl := &crudPolicyCtrl{owners: map[string]string{"1": "alice", "2": "bob"}}
+
+	tests := []struct {
+		notFound bool
+		method   string
+		path     string
+		subject  string
+		code     int
+	}{
+		{method: http.MethodGet, path: "/tests", code: http.StatusUnauthorized},
+		{method: http.MethodGet, path: "/tests", subject: "alice", code: http.StatusOK},
+		{method: http.MethodGet, path: "/tests", subject: "eve", code: http.StatusForbidden},
+		{method: http.MethodPost, path: "/tests", subject: "eve", code: http.StatusForbidden},
+		{method: http.MethodGet, path: "/tests/1", subject: "alice", code: http.StatusOK},
+		{method: http.MethodGet, path: "/tests/2", subject: "alice", code: http.StatusForbidden},
+		{method: http.MethodPatch, path: "/tests/2", subject: "alice", code: http.StatusForbidden},
+		{method: http.MethodDelete, path: "/tests/2", subject: "bob", code: http.StatusNoContent},
+		{notFound: true, method: http.MethodGet, path: "/tests", subject: "eve", code: http.StatusForbidden},
+		{notFound: true, method: http.MethodGet, path: "/tests/2", subject: "alice", code: http.StatusNotFound},
+		{notFound: true, method: http.MethodDelete, path: "/tests/1", subject: "bob", code: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		e := echo.New()
+		middlewarex.CRUDWithConfig(e.Group("", withSubject), "/tests", ctrl, middlewarex.CRUDConfig{
+			Policy: &middlewarex.CRUDPolicyConfig{NotFound: test.notFound},
+		})
+
+		req := httptest.NewRequest(test.method, test.path, nil)
+		if test.subject != "" {
+			req.Header.Set("X-Subject", test.subject)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, test.code, rec.Code, test.method+" "+test.path+" as "+test.subject)
+	}
+}
+
+func TestCRUDPolicyBulk(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUD(e.Group("", withSubject), "/tests", &crudPolicyCtrl{owners: map[string]string{"1": "alice", "2": "bob", "3": "alice"}})
+
+	req := httptest.NewRequest(http.MethodDelete, "/tests?ids=1,2,3", nil)
+	req.Header.Set("X-Subject", "alice")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+
+	var response middlewarex.BulkResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, []middlewarex.BulkResult{
+		{ID: "1", Status: http.StatusNoContent},
+		{ID: "2", Status: http.StatusForbidden, Error: "Forbidden"},
+		{ID: "3", Status: http.StatusNoContent},
+	}, response.Results)
+}
+
+type crudPolicyBulkUpdateCtrl struct {
+	crudPolicyCtrl
+}
+
+func (*crudPolicyBulkUpdateCtrl) BulkUpdate(_ *echo.Context, items []json.RawMessage) ([]middlewarex.BulkResult, error) {
+	results := make([]middlewarex.BulkResult, len(items))
+	for i, item := range items {
+		var v struct {
+			UUID float64 `json:"uuid"`
+		}
+		if err := json.Unmarshal(item, &v); err != nil {
+			return nil, err
+		}
+		results[i] = middlewarex.BulkResult{ID: strconv.FormatFloat(v.UUID, 'f', -1, 64), Status: http.StatusOK}
+	}
+	return results, nil
+}
+
+func TestCRUDPolicyBulkUpdate(t *testing.T) {
+	e := echo.New()
+	ctrl := &crudPolicyBulkUpdateCtrl{crudPolicyCtrl{owners: map[string]string{"1": "bob", "42": "alice"}}}
+	middlewarex.CRUDWithConfig(e.Group("", withSubject), "/tests", ctrl, middlewarex.CRUDConfig{IDParam: "uuid"})
+
+	req := httptest.NewRequest(http.MethodPatch, "/tests/_bulk", strings.NewReader(`[{"uuid":4.2e1},{"uuid":1},{"id":42,"uuid":1.0}]`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-Subject", "alice")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+
+	var response middlewarex.BulkResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, []middlewarex.BulkResult{
+		{ID: "42", Status: http.StatusOK},
+		{ID: "1", Status: http.StatusForbidden, Error: "Forbidden"},
+		{ID: "1", Status: http.StatusForbidden, Error: "Forbidden"},
+	}, response.Results)
+}
+
+type crudPurgeCtrl struct {
+	crudPolicyCtrl
+}
+
+func (*crudPurgeCtrl) Purge(c *echo.Context) error { return c.NoContent(http.StatusNoContent) }
+
+type crudPurgePolicyCtrl struct {
+	crudPurgeCtrl
+}
+
+func (*crudPurgePolicyCtrl) CanPurge(_ *echo.Context, token middlewarex.Token, _ string) (bool, error) {
+	return token.Subject == "admin", nil
+}
+
+func TestCRUDPolicyPurge(t *testing.T) {
+	owners := map[string]string{"1": "alice"}
+
+	purge := func(e *echo.Echo, subject string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/tests/1/purge", nil)
+		req.Header.Set("X-Subject", subject)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// CanDelete guards Purge without CanPurge
+	e := echo.New()
+	middlewarex.CRUD(e.Group("", withSubject), "/tests", &crudPurgeCtrl{crudPolicyCtrl{owners: owners}})
+	assert.Equal(t, http.StatusForbidden, purge(e, "admin"))
+	assert.Equal(t, http.StatusNoContent, purge(e, "alice"))
+
+	e = echo.New()
+	middlewarex.CRUD(e.Group("", withSubject), "/tests", &crudPurgePolicyCtrl{crudPurgeCtrl{crudPolicyCtrl{owners: owners}}})
+	assert.Equal(t, http.StatusForbidden, purge(e, "alice"))
+	assert.Equal(t, http.StatusNoContent, purge(e, "admin"))
+
+	// VerbWrite middlewares do not guard Purge
+	assert.PanicsWithValue(t, "Purge requires a VerbPurge middleware or a PolicySupported resource for /tests", func() {
+		middlewarex.CRUDWithConfig(echo.New().Group(""), "/tests", &crudSoftDeleteCtrl{}, middlewarex.CRUDConfig{
+			Middlewares: []middlewarex.VerbMiddleware{middlewarex.WithMiddleware(middlewarex.VerbWrite, withSubject)},
+		})
+	})
+}
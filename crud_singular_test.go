@@ -0,0 +1,95 @@
+package middlewarex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+type crudSingularCtrl struct{}
+
+func (*crudSingularCtrl) Create(c *echo.Context) error  { return c.String(http.StatusCreated, "create") }
+func (*crudSingularCtrl) List(c *echo.Context) error    { return c.String(http.StatusOK, "list") }
+func (*crudSingularCtrl) Show(c *echo.Context) error    { return c.String(http.StatusOK, "show") }
+func (*crudSingularCtrl) Update(c *echo.Context) error  { return c.String(http.StatusOK, "update") }
+func (*crudSingularCtrl) Replace(c *echo.Context) error { return c.String(http.StatusOK, "replace") }
+func (*crudSingularCtrl) Delete(c *echo.Context) error  { return c.String(http.StatusOK, "delete") }
+
+func (*crudSingularCtrl) Actions() []middlewarex.Action {
+	return []middlewarex.Action{{
+		Name:    "verify",
+		Member:  true,
+		Handler: func(c *echo.Context) error { return c.String(http.StatusOK, "verify") },
+	}}
+}
+
+func TestCRUDSingular(t *testing.T) {
+	e := echo.New()
+	var updates int
+	me := middlewarex.CRUDWithConfig(e.Group("/api"), "/me", &crudSingularCtrl{}, middlewarex.CRUDConfig{
+		Singular:    true,
+		IDValidator: func(string) error { return echo.ErrBadRequest },
+		Middlewares: []middlewarex.VerbMiddleware{middlewarex.WithMiddleware(middlewarex.VerbUpdate|middlewarex.VerbReplace, func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c *echo.Context) error {
+				updates++
+				return next(c)
+			}
+		})},
+	})
+	me.CRUD("/tasks", &crud1ctrl{})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{method: http.MethodGet, path: "/api/me", code: http.StatusOK, body: "show"},
+		{method: http.MethodPost, path: "/api/me", code: http.StatusCreated, body: "create"},
+		{method: http.MethodPatch, path: "/api/me", code: http.StatusOK, body: "update"},
+		{method: http.MethodPut, path: "/api/me", code: http.StatusOK, body: "replace"},
+		{method: http.MethodDelete, path: "/api/me", code: http.StatusOK, body: "delete"},
+		{method: http.MethodPost, path: "/api/me/verify", code: http.StatusOK, body: "verify"},
+		{method: http.MethodGet, path: "/api/me/1", code: http.StatusNotFound},
+		{method: http.MethodGet, path: "/api/me/tasks/1", code: http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, test.code, rec.Code, test.method+" "+test.path)
+		if test.body != "" {
+			assert.Equal(t, test.body, rec.Body.String(), test.method+" "+test.path)
+		}
+	}
+	assert.Equal(t, 2, updates)
+
+	info := me.Info()
+	assert.Empty(t, info.IDParam)
+	assert.Equal(t, "/api/me", info.Path)
+	assert.Equal(t, middlewarex.VerbCreate|middlewarex.VerbShow|middlewarex.VerbUpdate|middlewarex.VerbReplace|middlewarex.VerbDelete|middlewarex.VerbAction, info.Verbs)
+	assert.Equal(t, "/api/me/tasks", me.Children()[0].Info().Path)
+	assert.Equal(t, "me.tasks", me.Children()[0].Name())
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/me", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "OPTIONS, GET, POST, PUT, PATCH, DELETE", rec.Header().Get(echo.HeaderAllow))
+}
+
+func TestCRUDOfSingular(t *testing.T) {
+	config := middlewarex.TypedCRUDConfig[book, int]{}
+	config.Singular = true
+	assert.PanicsWithValue(t, "Singular is not supported by typed resources for /me", func() {
+		middlewarex.CRUDOfWithConfig(echo.New().Group(""), "/me", newBookRepository(), config)
+	})
+
+	parent := middlewarex.CRUD(echo.New().Group(""), "/users", &crudSingularCtrl{})
+	assert.PanicsWithValue(t, "Singular is not supported by typed resources for /users/:user_id/profile", func() {
+		parent.CRUDWithConfig("/profile", middlewarex.NewTypedResource(newBookRepository(), middlewarex.TypedCRUDConfig[book, int]{}), middlewarex.CRUDConfig{Singular: true})
+	})
+}
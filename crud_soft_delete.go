@@ -0,0 +1,75 @@
+package middlewarex
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+)
+
+// SoftDeleteSupported interface
+// SoftDelete is registered on `DELETE /path/:id' and takes precedence over Delete.
+// List and Show requests of soft deletable resources accept the `?include_deleted=true' query parameter (see IncludeDeleted).
+type SoftDeleteSupported interface {
+	SoftDelete(*echo.Context) error
+}
+
+// RestoreSupported interface
+// Restore is registered on `POST /path/:id/restore'.
+type RestoreSupported interface {
+	Restore(*echo.Context) error
+}
+
+// PurgeSupported interface
+// Purge permanently deletes a soft deleted value, it is registered on `DELETE /path/:id/purge'.
+// CRUD panics if no middleware registered for VerbPurge alone guards it (see WithMiddleware), unless the resource implements PolicySupported.
+type PurgeSupported interface {
+	Purge(*echo.Context) error
+}
+
+type includeDeletedContextKey struct{}
+
+const (
+	restorePath = "/restore"
+	purgePath   = "/purge"
+)
+
+// IncludeDeleted returns true when the List or Show request asks for the soft deleted values with `?include_deleted=true'.
+func IncludeDeleted(c *echo.Context) bool {
+	return IncludeDeletedFromContext(c.Request().Context())
+}
+
+// IncludeDeletedFromContext returns true when the request's context.Context asks for the soft deleted values (e.g. in a Repository).
+func IncludeDeletedFromContext(ctx context.Context) bool {
+	v, _ := ctx.Value(includeDeletedContextKey{}).(bool)
+	return v
+}
+
+// ContextWithIncludeDeleted returns a copy of ctx holding the given include_deleted value.
+func ContextWithIncludeDeleted(ctx context.Context, v bool) context.Context {
+	return context.WithValue(ctx, includeDeletedContextKey{}, v)
+}
+
+// withIncludeDeleted is a middleware parsing the `include_deleted' query parameter.
+// Invalid values are rejected with "400 - Bad Request" error.
+func withIncludeDeleted(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		params := c.QueryParams()
+		if !params.Has("include_deleted") {
+			return next(c)
+		}
+
+		v, err := strconv.ParseBool(params.Get("include_deleted"))
+		if err != nil {
+			return echo.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: "include_deleted must be a boolean",
+			}.Wrap(err)
+		}
+
+		r := c.Request()
+		c.SetRequest(r.WithContext(ContextWithIncludeDeleted(r.Context(), v)))
+		return next(c)
+	}
+}
@@ -0,0 +1,99 @@
+package middlewarex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+)
+
+type crudSoftDeleteCtrl struct {
+	deleted map[string]bool
+}
+
+func (ctrl *crudSoftDeleteCtrl) Show(c *echo.Context) error {
+	if ctrl.deleted[c.Param("id")] && !middlewarex.IncludeDeleted(c) {
+		return echo.ErrNotFound
+	}
+	return c.String(http.StatusOK, strconv.FormatBool(ctrl.deleted[c.Param("id")]))
+}
+
+func (*crudSoftDeleteCtrl) Delete(c *echo.Context) error {
+	return c.String(http.StatusOK, "hard")
+}
+
+func (ctrl *crudSoftDeleteCtrl) SoftDelete(c *echo.Context) error {
+	ctrl.deleted[c.Param("id")] = true
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (ctrl *crudSoftDeleteCtrl) Restore(c *echo.Context) error {
+	ctrl.deleted[c.Param("id")] = false
+	return c.NoContent(http.StatusOK)
+}
+
+func (ctrl *crudSoftDeleteCtrl) Purge(c *echo.Context) error {
+	delete(ctrl.deleted, c.Param("id"))
+	return c.NoContent(http.StatusNoContent)
+}
+
+func TestCRUDSoftDelete(t *testing.T) {
+	admin := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			if c.Request().Header.Get("X-Admin") == "" {
+				return echo.ErrForbidden
+			}
+			return next(c)
+		}
+	}
+
+	e := echo.New()
+	ctrl := &crudSoftDeleteCtrl{deleted: map[string]bool{}}
+	middlewarex.CRUDWithConfig(e.Group(""), "/tests", ctrl, middlewarex.CRUDConfig{
+		Middlewares: []middlewarex.VerbMiddleware{middlewarex.WithMiddleware(middlewarex.VerbPurge, admin)},
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		admin  bool
+		code   int
+		body   string
+	}{
+		{method: http.MethodDelete, path: "/tests/1", code: http.StatusNoContent},
+		{method: http.MethodGet, path: "/tests/1", code: http.StatusNotFound},
+		{method: http.MethodGet, path: "/tests/1?include_deleted=true", code: http.StatusOK, body: "true"},
+		{method: http.MethodGet, path: "/tests/1?include_deleted=maybe", code: http.StatusBadRequest},
+		{method: http.MethodPost, path: "/tests/1/restore", code: http.StatusOK},
+		{method: http.MethodGet, path: "/tests/1", code: http.StatusOK, body: "false"},
+		{method: http.MethodDelete, path: "/tests/1/purge", code: http.StatusForbidden},
+		{method: http.MethodDelete, path: "/tests/1/purge", admin: true, code: http.StatusNoContent},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		if test.admin {
+			req.Header.Set("X-Admin", "true")
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != test.code {
+			t.Fatalf("Expecting %d for %s %s but got %d", test.code, test.method, test.path, rec.Code)
+		}
+		if test.body != "" && rec.Body.String() != test.body {
+			t.Fatalf("Expecting %q for %s %s but got %q", test.body, test.method, test.path, rec.Body.String())
+		}
+	}
+	if _, ok := ctrl.deleted["1"]; ok {
+		t.Fatal("Expecting the value to be purged")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expecting a panic for an unguarded purge")
+		}
+	}()
+	middlewarex.CRUD(echo.New().Group(""), "/tests", ctrl)
+}
@@ -3,6 +3,7 @@ package middlewarex_test
 import (
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -39,7 +40,7 @@ func TestCRUD(t *testing.T) {
 
 	seen := map[string]bool{}
 	for _, route := range e.Router().Routes() {
-		if !strings.HasPrefix(route.Path, "/tests") {
+		if !strings.HasPrefix(route.Path, "/tests") || route.Method == echo.RouteNotFound {
 			continue
 		}
 
@@ -92,7 +93,7 @@ func TestCRUDWithoutAllMethods(t *testing.T) {
 
 	seen := map[string]bool{}
 	for _, route := range e.Router().Routes() {
-		if !strings.HasPrefix(route.Path, "/tests") {
+		if !strings.HasPrefix(route.Path, "/tests") || route.Method == echo.RouteNotFound {
 			continue
 		}
 
@@ -117,3 +118,336 @@ func TestCRUDWithoutAllMethods(t *testing.T) {
 		t.Fatal("Expecting Delete handler to be defined")
 	}
 }
+
+func TestCRUDWithConfig(t *testing.T) {
+	e := echo.New()
+	router := e.Group("")
+	middlewarex.CRUDWithConfig(router, "/tests", &crud1ctrl{}, middlewarex.CRUDConfig{
+		IDParam:     "uuid",
+		IDValidator: middlewarex.ValidateUUID,
+	})
+
+	seen := map[string]bool{}
+	for _, route := range e.Router().Routes() {
+		seen[fmt.Sprintf("%s %s", route.Method, route.Path)] = true
+	}
+	for _, v := range []string{"GET /tests/:uuid", "PATCH /tests/:uuid", "DELETE /tests/:uuid"} {
+		if !seen[v] {
+			t.Fatalf("Expecting %s handler to be defined", v)
+		}
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{method: http.MethodGet, path: "/tests/123e4567-e89b-12d3-a456-426614174000", code: http.StatusOK},
+		{method: http.MethodGet, path: "/tests/42", code: http.StatusBadRequest},
+		{method: http.MethodPatch, path: "/tests/42", code: http.StatusBadRequest},
+		{method: http.MethodDelete, path: "/tests/42", code: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/tests", code: http.StatusOK},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(test.method, test.path, nil))
+		if rec.Code != test.code {
+			t.Fatalf("Expecting %d for %s %s but got %d", test.code, test.method, test.path, rec.Code)
+		}
+	}
+}
+
+func TestIDValidators(t *testing.T) {
+	tests := []struct {
+		validator middlewarex.IDValidator
+		id        string
+		valid     bool
+	}{
+		{validator: middlewarex.ValidateUUID, id: "123e4567-e89b-12d3-a456-426614174000", valid: true},
+		{validator: middlewarex.ValidateUUID, id: "123e4567e89b12d3a456426614174000", valid: false},
+		{validator: middlewarex.ValidateInteger, id: "42", valid: true},
+		{validator: middlewarex.ValidateInteger, id: "4.2", valid: false},
+		{validator: middlewarex.ValidateRegexp(`^[a-z0-9-]+$`), id: "my-slug", valid: true},
+		{validator: middlewarex.ValidateRegexp(`^[a-z0-9-]+$`), id: "My Slug", valid: false},
+	}
+
+	for _, test := range tests {
+		if err := test.validator(test.id); (err == nil) != test.valid {
+			t.Fatalf("Expecting %q validity to be %t but got %v", test.id, test.valid, err)
+		}
+	}
+}
+
+type crud3ctrl struct {
+	crud1ctrl
+}
+
+func (*crud3ctrl) Replace(c *echo.Context) error {
+	return c.String(http.StatusOK, "replace")
+}
+
+func TestCRUDUpdateMethods(t *testing.T) {
+	tests := []struct {
+		resource any
+		methods  []string
+		expected map[string]string // method => response
+	}{
+		{
+			resource: &crud1ctrl{},
+			expected: map[string]string{http.MethodPatch: "200 ", http.MethodPut: "405"},
+		},
+		{
+			resource: &crud1ctrl{},
+			methods:  []string{http.MethodPut},
+			expected: map[string]string{http.MethodPatch: "405", http.MethodPut: "200 "},
+		},
+		{
+			resource: &crud1ctrl{},
+			methods:  []string{http.MethodPatch, http.MethodPut},
+			expected: map[string]string{http.MethodPatch: "200 ", http.MethodPut: "200 "},
+		},
+		{
+			resource: &crud3ctrl{},
+			methods:  []string{http.MethodPatch, http.MethodPut},
+			expected: map[string]string{http.MethodPatch: "200 ", http.MethodPut: "200 replace"},
+		},
+	}
+
+	for _, test := range tests {
+		e := echo.New()
+		middlewarex.CRUDWithConfig(e.Group(""), "/tests", test.resource, middlewarex.CRUDConfig{
+			UpdateMethods: test.methods,
+		})
+
+		for method, expected := range test.expected {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(method, "/tests/42", nil))
+			if v := fmt.Sprintf("%d %s", rec.Code, rec.Body.String()); !strings.HasPrefix(v, expected) {
+				t.Fatalf("Expecting %s %v to respond %q but got %q", method, test.methods, expected, v)
+			}
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expecting a panic for an unsupported update method")
+		}
+	}()
+	middlewarex.CRUDWithConfig(echo.New().Group(""), "/tests", &crud1ctrl{}, middlewarex.CRUDConfig{
+		UpdateMethods: []string{http.MethodPost},
+	})
+}
+
+type crudNestedCtrl struct{}
+
+func (*crudNestedCtrl) List(c *echo.Context) error {
+	return c.String(http.StatusOK, strings.Join(middlewarex.ParentIDs(c), ","))
+}
+
+func (*crudNestedCtrl) Show(c *echo.Context) error {
+	return c.String(http.StatusOK, strings.Join(append(middlewarex.ParentIDs(c), c.Param("id")), ","))
+}
+
+func TestCRUDNested(t *testing.T) {
+	e := echo.New()
+	router := e.Group("")
+	projects := middlewarex.CRUDWithConfig(router, "/projects", &crud1ctrl{}, middlewarex.CRUDConfig{
+		IDValidator: middlewarex.ValidateInteger,
+	})
+	tasks := projects.CRUD("/tasks", &crud1ctrl{})
+	tasks.CRUD("/comments", &crudNestedCtrl{})
+
+	seen := map[string]bool{}
+	for _, route := range e.Router().Routes() {
+		seen[fmt.Sprintf("%s %s", route.Method, route.Path)] = true
+	}
+	for _, v := range []string{
+		"GET /projects/:id",
+		"POST /projects/:project_id/tasks",
+		"GET /projects/:project_id/tasks/:id",
+		"GET /projects/:project_id/tasks/:task_id/comments",
+		"GET /projects/:project_id/tasks/:task_id/comments/:id",
+	} {
+		if !seen[v] {
+			t.Fatalf("Expecting %s handler to be defined", v)
+		}
+	}
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{path: "/projects/1/tasks/2/comments", code: http.StatusOK, body: "1,2"},
+		{path: "/projects/1/tasks/2/comments/3", code: http.StatusOK, body: "1,2,3"},
+		{path: "/projects/one/tasks/2/comments/3", code: http.StatusBadRequest},
+		{path: "/projects/one/tasks", code: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if rec.Code != test.code {
+			t.Fatalf("Expecting %d for %s but got %d", test.code, test.path, rec.Code)
+		}
+		if test.body != "" && rec.Body.String() != test.body {
+			t.Fatalf("Expecting %q for %s but got %q", test.body, test.path, rec.Body.String())
+		}
+	}
+}
+
+type crudLocationCtrl struct{}
+
+func (*crudLocationCtrl) Create(c *echo.Context) error {
+	location, err := middlewarex.ResourceURL(c, "projects.tasks.show", 42)
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderLocation, location)
+	return c.NoContent(http.StatusCreated)
+}
+
+func (*crudLocationCtrl) Show(c *echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+func TestCRUDRouteNames(t *testing.T) {
+	e := echo.New()
+	router := e.Group("/api")
+	projects := middlewarex.CRUD(router, "/projects", &crud1ctrl{})
+	tasks := projects.CRUD("/tasks", &crudLocationCtrl{})
+	middlewarex.CRUDWithConfig(router, "/people", &crud1ctrl{}, middlewarex.CRUDConfig{Name: "users"})
+
+	if tasks.Name() != "projects.tasks" {
+		t.Fatalf("Expecting projects.tasks name but got %s", tasks.Name())
+	}
+	if len(projects.Routes()) != 5 || len(tasks.Routes()) != 2 {
+		t.Fatalf("Expecting 5 and 2 routes but got %d and %d", len(projects.Routes()), len(tasks.Routes()))
+	}
+
+	names := map[string]string{}
+	for _, route := range e.Router().Routes() {
+		names[route.Name] = route.Method + " " + route.Path
+	}
+	for name, v := range map[string]string{
+		"projects.create":       "POST /api/projects",
+		"projects.list":         "GET /api/projects",
+		"projects.show":         "GET /api/projects/:id",
+		"projects.update":       "PATCH /api/projects/:id",
+		"projects.delete":       "DELETE /api/projects/:id",
+		"projects.tasks.show":   "GET /api/projects/:project_id/tasks/:id",
+		"users.list":            "GET /api/people",
+		"projects.tasks.create": "POST /api/projects/:project_id/tasks",
+	} {
+		if names[name] != v {
+			t.Fatalf("Expecting %s route to be %s but got %q", name, v, names[name])
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/projects/a%20b/tasks", nil))
+	if location := rec.Header().Get(echo.HeaderLocation); location != "/api/projects/a%20b/tasks/42" {
+		t.Fatalf("Expecting Location /api/projects/a%%20b/tasks/42 but got %q", location)
+	}
+
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	if u, err := middlewarex.ResourceURL(c, "projects.tasks.show", 1, 2); err != nil || u != "/api/projects/1/tasks/2" {
+		t.Fatalf("Expecting /api/projects/1/tasks/2 but got %q (%v)", u, err)
+	}
+	if _, err := middlewarex.ResourceURL(c, "projects.show", 1, 2); err == nil {
+		t.Fatal("Expecting an error for too many IDs")
+	}
+	if _, err := middlewarex.ResourceURL(c, "unknown.show", 1); err == nil {
+		t.Fatal("Expecting an error for an unknown route")
+	}
+}
+
+type crudVersionedCtrl struct{}
+
+func (*crudVersionedCtrl) Create(c *echo.Context) error {
+	location, err := middlewarex.ResourceURL(c, "tests.show", 42)
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderLocation, location)
+	return c.NoContent(http.StatusCreated)
+}
+
+func (*crudVersionedCtrl) Show(c *echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+func TestResourceURLVersions(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUD(e.Group("/v1"), "/tests", &crudVersionedCtrl{})
+	middlewarex.CRUD(e.Group("/v2"), "/tests", &crudVersionedCtrl{})
+
+	for _, version := range []string{"/v1", "/v2"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, version+"/tests", nil))
+		if location := rec.Header().Get(echo.HeaderLocation); location != version+"/tests/42" {
+			t.Fatalf("Expecting Location %s/tests/42 but got %q", version, location)
+		}
+	}
+}
+
+func TestCRUDMiddlewares(t *testing.T) {
+	auth := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			if c.Request().Header.Get(echo.HeaderAuthorization) == "" {
+				return echo.ErrUnauthorized
+			}
+			return next(c)
+		}
+	}
+	var order []string
+	trace := func(name string) echo.MiddlewareFunc {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c *echo.Context) error {
+				order = append(order, name)
+				return next(c)
+			}
+		}
+	}
+
+	e := echo.New()
+	middlewarex.CRUDWithConfig(e.Group(""), "/tests", &crud1ctrl{}, middlewarex.CRUDConfig{
+		IDValidator: middlewarex.ValidateInteger,
+		Middlewares: []middlewarex.VerbMiddleware{
+			middlewarex.WithMiddleware(middlewarex.VerbCreate|middlewarex.VerbUpdate|middlewarex.VerbDelete, auth),
+			middlewarex.WithMiddleware(middlewarex.VerbShow, trace("first"), trace("second")),
+		},
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		auth   bool
+		code   int
+	}{
+		{method: http.MethodGet, path: "/tests", code: http.StatusOK},
+		{method: http.MethodGet, path: "/tests/1", code: http.StatusOK},
+		{method: http.MethodPost, path: "/tests", code: http.StatusUnauthorized},
+		{method: http.MethodPost, path: "/tests", auth: true, code: http.StatusOK},
+		{method: http.MethodPatch, path: "/tests/1", code: http.StatusUnauthorized},
+		{method: http.MethodDelete, path: "/tests/one", code: http.StatusUnauthorized},
+		{method: http.MethodDelete, path: "/tests/one", auth: true, code: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		if test.auth {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != test.code {
+			t.Fatalf("Expecting %d for %s %s but got %d", test.code, test.method, test.path, rec.Code)
+		}
+	}
+
+	if strings.Join(order, ",") != "first,second" {
+		t.Fatalf("Expecting middlewares to run in order but got %v", order)
+	}
+	if v := (middlewarex.VerbCreate | middlewarex.VerbBulkDelete).String(); v != "create|bulk_delete" {
+		t.Fatalf("Expecting create|bulk_delete but got %s", v)
+	}
+}
@@ -0,0 +1,418 @@
+package middlewarex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/labstack/echo/v5"
+)
+
+type (
+	// Repository defines the storage used by a typed CRUD resource.
+	Repository[T any, ID comparable] interface {
+		// Create stores the given value and sets its generated fields (e.g. its ID).
+		Create(ctx context.Context, v *T) error
+		// List returns all the values.
+		List(ctx context.Context) ([]T, error)
+		// Get returns the value identified by id or ErrNotFound.
+		Get(ctx context.Context, id ID) (T, error)
+		// Update replaces the value identified by id or returns ErrNotFound.
+		Update(ctx context.Context, id ID, v *T) error
+		// Delete removes the value identified by id or returns ErrNotFound.
+		Delete(ctx context.Context, id ID) error
+	}
+
+	// PagedRepository is an optional Repository interface used by typed List handlers
+	// when the ListQuery parsing is enabled (see CRUDConfig.ListQuery).
+	PagedRepository[T any] interface {
+		// ListPage returns the values matching the given ListQuery.
+		ListPage(ctx context.Context, q ListQuery) ([]T, ListResult, error)
+	}
+
+	// TypedCRUDConfig defines the config for typed CRUD registration.
+	TypedCRUDConfig[T any, ID comparable] struct {
+		CRUDConfig
+
+		// ParseID parses the ID path parameter.
+		// Optional. Default value parses the ID with echo.ParseValue.
+		ParseID func(id string) (ID, error)
+
+		// Bind binds and validates the request body into v.
+		// Optional. Default value uses the Echo's Binder and Validator (when registered).
+		Bind func(c *echo.Context, v *T) error
+
+		// Render renders v (a T or a []T) with the given status code.
+		// Optional. Default value renders v as JSON.
+		Render func(c *echo.Context, code int, v any) error
+
+		// MapError maps the errors returned by the Repository to HTTP errors.
+		// Optional. Default value DefaultTypedMapError.
+		MapError func(err error) error
+
+		// JSONAPI renders the values and the errors as JSON:API documents and binds JSON:API request bodies.
+		// It replaces the default Bind and Render, the errors are rendered by the JSONAPIErrors middleware
+		// added by CRUDOfWithConfig (it must be added to CRUDConfig.Middlewares when using NewTypedResource).
+		// Optional.
+		JSONAPI *JSONAPIConfig[T]
+	}
+
+	// TypedResource is a CRUD resource generated from a Repository.
+	// It implements Create, List, Show, Update (partial update), Replace and Delete.
+	TypedResource[T any, ID comparable] struct {
+		repository Repository[T, ID]
+		config     TypedCRUDConfig[T, ID]
+	}
+)
+
+// ErrNotFound is returned by a Repository when the requested value does not exist.
+var ErrNotFound = errors.New("not found")
+
+// CRUDOf defines the CRUD resources backed by the given repository.
+func CRUDOf[T any, ID comparable](group *echo.Group, path string, repository Repository[T, ID]) *Resource {
+	return CRUDOfWithConfig(group, path, repository, TypedCRUDConfig[T, ID]{CRUDConfig: DefaultCRUDConfig})
+}
+
+// CRUDOfWithConfig defines the CRUD resources backed by the given repository with config.
+func CRUDOfWithConfig[T any, ID comparable](group *echo.Group, path string, repository Repository[T, ID], config TypedCRUDConfig[T, ID]) *Resource {
+	if config.JSONAPI != nil {
+		middlewares := []VerbMiddleware{WithMiddleware(VerbAll, JSONAPIErrors)}
+		config.Middlewares = append(middlewares, config.Middlewares...)
+	}
+	return CRUDWithConfig(group, path, NewTypedResource(repository, config), config.CRUDConfig)
+}
+
+// NewTypedResource returns a CRUD resource backed by the given repository.
+// It is useful to register typed nested resources with Resource.CRUDWithConfig.
+func NewTypedResource[T any, ID comparable](repository Repository[T, ID], config TypedCRUDConfig[T, ID]) *TypedResource[T, ID] {
+	// Defaults
+	if config.IDParam == "" {
+		config.IDParam = DefaultCRUDConfig.IDParam
+	}
+	if config.ParseID == nil {
+		config.ParseID = func(id string) (ID, error) {
+			return echo.ParseValue[ID](id)
+		}
+	}
+	if config.JSONAPI != nil {
+		jsonapi := config.JSONAPI.defaults()
+		config.Bind = jsonapi.bind
+		config.Render = jsonapi.render
+	}
+	if config.Bind == nil {
+		config.Bind = defaultTypedBind[T]
+	}
+	if config.Render == nil {
+		config.Render = defaultTypedRender
+	}
+	if config.MapError == nil {
+		config.MapError = DefaultTypedMapError
+	}
+
+	return &TypedResource[T, ID]{
+		repository: repository,
+		config:     config,
+	}
+}
+
+// Type returns the type of the resource's values.
+func (r *TypedResource[T, ID]) Type() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+// IDType returns the type of the resource's IDs.
+func (r *TypedResource[T, ID]) IDType() reflect.Type {
+	return reflect.TypeFor[ID]()
+}
+
+// jsonAPIType returns the JSON:API type of the resource objects, empty when the resource is not rendered as JSON:API.
+func (r *TypedResource[T, ID]) jsonAPIType() string {
+	if r.config.JSONAPI == nil {
+		return ""
+	}
+	return r.config.JSONAPI.Type
+}
+
+// Create binds the request body, stores it and renders it with "201 - Created" status.
+func (r *TypedResource[T, ID]) Create(c *echo.Context) error {
+	var v T
+	if err := r.config.Bind(c, &v); err != nil {
+		return err
+	}
+
+	if err := r.repository.Create(c.Request().Context(), &v); err != nil {
+		return r.config.MapError(err)
+	}
+	if err := r.setETag(c, v); err != nil {
+		return err
+	}
+	return r.config.Render(c, http.StatusCreated, v)
+}
+
+// List renders all the values.
+// When the ListQuery parsing is enabled and the repository is a PagedRepository, it renders the requested page
+// and sets the pagination headers.
+func (r *TypedResource[T, ID]) List(c *echo.Context) error {
+	ctx := c.Request().Context()
+
+	var values []T
+	var err error
+	q, paged := ListQueryFromContext(ctx)
+	repository, ok := r.repository.(PagedRepository[T])
+	if paged = paged && ok; paged {
+		var result ListResult
+		if values, result, err = repository.ListPage(ctx, q); err != nil {
+			return r.config.MapError(err)
+		}
+		SetListHeaders(c, q, result)
+		c.Set(listResultContextKey, result)
+	} else if values, err = r.repository.List(ctx); err != nil {
+		return r.config.MapError(err)
+	}
+	if values == nil {
+		values = []T{}
+	}
+	return r.config.Render(c, http.StatusOK, values)
+}
+
+// Show renders the value identified by the ID path parameter.
+func (r *TypedResource[T, ID]) Show(c *echo.Context) error {
+	id, err := r.id(c)
+	if err != nil {
+		return err
+	}
+
+	v, err := r.repository.Get(c.Request().Context(), id)
+	if err != nil {
+		return r.config.MapError(err)
+	}
+	return r.config.Render(c, http.StatusOK, v)
+}
+
+// Update binds the request body on top of the stored value and updates it.
+// JSON Merge Patch and JSON Patch bodies are applied to the JSON representation of the stored value (see PatchJSON).
+func (r *TypedResource[T, ID]) Update(c *echo.Context) error {
+	id, err := r.id(c)
+	if err != nil {
+		return err
+	}
+
+	v, err := r.repository.Get(c.Request().Context(), id)
+	if err != nil {
+		return r.config.MapError(err)
+	}
+	if IsPatch(c) {
+		err = r.patch(c, &v)
+	} else {
+		err = r.config.Bind(c, &v)
+	}
+	if err != nil {
+		return err
+	}
+
+	return r.update(c, id, &v)
+}
+
+// Replace binds the request body and replaces the stored value, the fields hidden from JSON are kept.
+func (r *TypedResource[T, ID]) Replace(c *echo.Context) error {
+	id, err := r.id(c)
+	if err != nil {
+		return err
+	}
+
+	stored, err := r.repository.Get(c.Request().Context(), id)
+	if err != nil {
+		return r.config.MapError(err)
+	}
+	var v T
+	if err = r.config.Bind(c, &v); err != nil {
+		return err
+	}
+	mergeTyped(&stored, v)
+
+	return r.update(c, id, &stored)
+}
+
+// Delete removes the value identified by the ID path parameter and responds with "204 - No Content" status.
+func (r *TypedResource[T, ID]) Delete(c *echo.Context) error {
+	id, err := r.id(c)
+	if err != nil {
+		return err
+	}
+
+	if err = r.repository.Delete(c.Request().Context(), id); err != nil {
+		return r.config.MapError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (r *TypedResource[T, ID]) update(c *echo.Context, id ID, v *T) error {
+	if err := r.repository.Update(c.Request().Context(), id, v); err != nil {
+		return r.config.MapError(err)
+	}
+	if err := r.setETag(c, *v); err != nil {
+		return err
+	}
+	return r.config.Render(c, http.StatusOK, *v)
+}
+
+// patch applies the request's patch to v and validates the patched value.
+func (r *TypedResource[T, ID]) patch(c *echo.Context, v *T) error {
+	document, err := json.Marshal(*v)
+	if err != nil {
+		return err
+	}
+	if document, err = PatchJSON(c, document); err != nil {
+		return err
+	}
+
+	var patched T
+	if err = json.Unmarshal(document, &patched); err != nil {
+		return echo.HTTPError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "patched value is invalid",
+		}.Wrap(err)
+	}
+	mergeTyped(v, patched)
+	return validateTyped(c, v)
+}
+
+// mergeTyped sets dst to src, the fields hidden from JSON (unexported or tagged `json:"-"') keep their dst value.
+func mergeTyped[T any](dst *T, src T) {
+	if reflect.TypeFor[T]().Kind() != reflect.Struct {
+		*dst = src
+		return
+	}
+	mergeJSONFields(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src))
+}
+
+// mergeJSONFields sets the JSON fields of the dst struct from src.
+func mergeJSONFields(dst, src reflect.Value) {
+	t := dst.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		if field.Anonymous && tag == "" {
+			switch {
+			case field.Type.Kind() == reflect.Struct:
+				mergeJSONFields(dst.Field(i), src.Field(i))
+				continue
+			case field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct && field.IsExported():
+				dst.Field(i).Set(src.Field(i))
+				continue
+			}
+		}
+		if field.IsExported() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
+
+// repositoryBacked marks the resources whose values are identified by the ID path parameter, they cannot be singular.
+func (*TypedResource[T, ID]) repositoryBacked() {}
+
+// ETag returns the entity tag of the value identified by the ID path parameter (see ValueETag).
+func (r *TypedResource[T, ID]) ETag(c *echo.Context) (string, error) {
+	id, err := r.id(c)
+	if err != nil {
+		return "", err
+	}
+
+	v, err := r.repository.Get(c.Request().Context(), id)
+	if err != nil {
+		return "", r.config.MapError(err)
+	}
+	return r.valueETag(v)
+}
+
+// valueETag returns the entity tag of the value (see ValueETag), the hash ignores the hidden fields (see CRUDConfig.Fields).
+func (r *TypedResource[T, ID]) valueETag(v T) (string, error) {
+	if _, ok := any(v).(Versioned); ok || r.config.Fields == nil || len(r.config.Fields.Hidden) == 0 {
+		return ValueETag(v)
+	}
+
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	hidden := hiddenFields(*r.config.Fields)
+	if payload, err = rewriteJSON(payload, func(v any) any {
+		hidden.remove(v)
+		return v
+	}); err != nil {
+		return "", err
+	}
+	return hashETag(payload), nil
+}
+
+// setETag sets the ETag header of a created or updated value when CRUDConfig.ETag is enabled.
+func (r *TypedResource[T, ID]) setETag(c *echo.Context, v T) error {
+	if !r.config.ETag {
+		return nil
+	}
+
+	etag, err := r.valueETag(v)
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(headerETag, etag)
+	return nil
+}
+
+func (r *TypedResource[T, ID]) id(c *echo.Context) (ID, error) {
+	param := r.config.IDParam
+	id, err := r.config.ParseID(c.Param(param))
+	if err != nil {
+		return id, echo.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "invalid " + param,
+		}.Wrap(err)
+	}
+	return id, nil
+}
+
+// DefaultTypedMapError maps ErrNotFound to "404 - Not Found" error,
+// ErrConflict to "409 - Conflict" error and ErrUnknownField or ErrInvalidCursor to "400 - Bad Request" error.
+// HTTP errors and other errors are returned as is.
+func DefaultTypedMapError(err error) error {
+	var herr *echo.HTTPError
+	switch {
+	case errors.As(err, &herr):
+		return err
+	case errors.Is(err, ErrNotFound):
+		return echo.ErrNotFound.Wrap(err)
+	case errors.Is(err, ErrConflict):
+		return echo.HTTPError{Code: http.StatusConflict, Message: err.Error()}.Wrap(err)
+	case errors.Is(err, ErrUnknownField), errors.Is(err, ErrInvalidCursor):
+		return echo.HTTPError{Code: http.StatusBadRequest, Message: err.Error()}.Wrap(err)
+	}
+	return err
+}
+
+func defaultTypedBind[T any](c *echo.Context, v *T) error {
+	if err := echo.BindBody(c, v); err != nil {
+		return err
+	}
+	return validateTyped(c, v)
+}
+
+// validateTyped validates v with the Echo's Validator (when registered).
+func validateTyped[T any](c *echo.Context, v *T) error {
+	if err := c.Validate(v); err != nil && !errors.Is(err, echo.ErrValidatorNotRegistered) {
+		return echo.HTTPError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}.Wrap(err)
+	}
+	return nil
+}
+
+func defaultTypedRender(c *echo.Context, code int, v any) error {
+	return c.JSON(code, v)
+}
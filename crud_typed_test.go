@@ -0,0 +1,193 @@
+package middlewarex_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+type book struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Author string `json:"author"`
+}
+
+// bookRepository is a minimal Repository used to test the typed CRUD handlers.
+type bookRepository struct {
+	books map[int]book
+	next  int
+}
+
+func newBookRepository(books ...book) *bookRepository {
+	r := &bookRepository{books: map[int]book{}}
+	for _, b := range books {
+		r.books[b.ID] = b
+		r.next = max(r.next, b.ID)
+	}
+	return r
+}
+
+func (r *bookRepository) Create(_ context.Context, v *book) error {
+	r.next++
+	v.ID = r.next
+	r.books[v.ID] = *v
+	return nil
+}
+
+func (r *bookRepository) List(context.Context) ([]book, error) {
+	var books []book
+	for _, b := range r.books {
+		books = append(books, b)
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+	return books, nil
+}
+
+func (r *bookRepository) Get(_ context.Context, id int) (book, error) {
+	b, ok := r.books[id]
+	if !ok {
+		return b, middlewarex.ErrNotFound
+	}
+	return b, nil
+}
+
+func (r *bookRepository) Update(_ context.Context, id int, v *book) error {
+	if _, ok := r.books[id]; !ok {
+		return middlewarex.ErrNotFound
+	}
+	v.ID = id
+	r.books[id] = *v
+	return nil
+}
+
+func (r *bookRepository) Delete(_ context.Context, id int) error {
+	if _, ok := r.books[id]; !ok {
+		return middlewarex.ErrNotFound
+	}
+	delete(r.books, id)
+	return nil
+}
+
+func serve(e *echo.Echo, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCRUDOf(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUDOf(e.Group(""), "/books", newBookRepository(book{ID: 1, Title: "Dune", Author: "Frank Herbert"}))
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		code   int
+		exp    string
+	}{
+		{method: http.MethodGet, path: "/books", code: http.StatusOK, exp: `[{"id":1,"title":"Dune","author":"Frank Herbert"}]`},
+		{method: http.MethodPost, path: "/books", body: `{"title":"Hyperion","author":"Dan Simmons"}`, code: http.StatusCreated, exp: `{"id":2,"title":"Hyperion","author":"Dan Simmons"}`},
+		{method: http.MethodPost, path: "/books", body: `{"title":`, code: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/books/2", code: http.StatusOK, exp: `{"id":2,"title":"Hyperion","author":"Dan Simmons"}`},
+		{method: http.MethodGet, path: "/books/42", code: http.StatusNotFound},
+		{method: http.MethodGet, path: "/books/two", code: http.StatusBadRequest},
+		{method: http.MethodPatch, path: "/books/2", body: `{"title":"The Fall of Hyperion"}`, code: http.StatusOK, exp: `{"id":2,"title":"The Fall of Hyperion","author":"Dan Simmons"}`},
+		{method: http.MethodPatch, path: "/books/42", body: `{"title":"Foundation"}`, code: http.StatusNotFound},
+		{method: http.MethodPut, path: "/books/2", body: `{"title":"Endymion"}`, code: http.StatusOK, exp: `{"id":2,"title":"Endymion","author":""}`},
+		{method: http.MethodDelete, path: "/books/2", code: http.StatusNoContent},
+		{method: http.MethodDelete, path: "/books/2", code: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		rec := serve(e, test.method, test.path, test.body)
+		info := test.method + " " + test.path
+		assert.Equal(t, test.code, rec.Code, info)
+		if test.exp != "" {
+			assert.JSONEq(t, test.exp, rec.Body.String(), info)
+		}
+	}
+}
+
+func TestCRUDOfWithConfig(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUDOfWithConfig(e.Group(""), "/books", &forbiddenDeleteRepository{newBookRepository(book{ID: 1})}, middlewarex.TypedCRUDConfig[book, int]{
+		Bind: func(c *echo.Context, v *book) error {
+			if err := c.Bind(v); err != nil {
+				return err
+			}
+			if v.Title == "" {
+				return echo.NewHTTPError(http.StatusUnprocessableEntity, "title is required")
+			}
+			return nil
+		},
+		Render: func(c *echo.Context, code int, v any) error {
+			return c.JSON(code, map[string]any{"data": v})
+		},
+		MapError: func(err error) error {
+			if errors.Is(err, errForbidden) {
+				return echo.ErrForbidden.Wrap(err)
+			}
+			return middlewarex.DefaultTypedMapError(err)
+		},
+	})
+
+	rec := serve(e, http.MethodPost, "/books", `{"author":"Anonymous"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = serve(e, http.MethodGet, "/books/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"id":1,"title":"","author":""}}`, rec.Body.String())
+
+	rec = serve(e, http.MethodDelete, "/books/1", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = serve(e, http.MethodGet, "/books/2", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+var errForbidden = errors.New("forbidden")
+
+type forbiddenDeleteRepository struct {
+	*bookRepository
+}
+
+func (*forbiddenDeleteRepository) Delete(context.Context, int) error {
+	return errForbidden
+}
+
+func TestCRUDOfReplaceHiddenFields(t *testing.T) {
+	type account struct {
+		ID           int    `json:"id"`
+		Name         string `json:"name"`
+		PasswordHash string `json:"-"`
+		owner        string
+	}
+
+	repository := middlewarex.NewMemoryRepository[account, int]()
+	assert.NoError(t, repository.Create(context.Background(), &account{Name: "a", PasswordHash: "h4sh", owner: "alice"}))
+	e := echo.New()
+	middlewarex.CRUDOf(e.Group(""), "/accounts", repository)
+
+	rec := serve(e, http.MethodPut, "/accounts/1", `{"name":"b"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":1,"name":"b"}`, rec.Body.String())
+
+	v, err := repository.Get(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, account{ID: 1, Name: "b", PasswordHash: "h4sh", owner: "alice"}, v)
+
+	rec = serve(e, http.MethodPut, "/accounts/42", `{"name":"b"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,127 @@
+package middlewarex
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/o1egl/paseto/v2"
+)
+
+type (
+	// CursorState is the pagination state sealed in an opaque cursor.
+	CursorState struct {
+		// SortKey is the sort key of the last item of the page.
+		SortKey string `json:"sk,omitempty"`
+		// LastID is the ID of the last item of the page.
+		LastID string `json:"id,omitempty"`
+		// Offset is the number of items already returned.
+		Offset int `json:"o,omitempty"`
+		// QueryHash is the hash of the sort and filter of the ListQuery the cursor belongs to.
+		QueryHash string `json:"qh"`
+		// ExpiresAt is the expiration time of the cursor.
+		ExpiresAt time.Time `json:"exp"`
+	}
+
+	// CursorCodec seals pagination states into opaque cursors using PASETO v2.local encryption.
+	// Cursors can't be read or tampered with by clients and are bound to the query they were created for.
+	// The encryption key is derived from the given key, so the cursors are not valid PASETO tokens of that key.
+	CursorCodec struct {
+		key []byte
+		ttl time.Duration
+	}
+)
+
+// DefaultCursorTTL is the default lifetime of the cursors.
+const DefaultCursorTTL = time.Hour
+
+const (
+	cursorHeader  = "v2.local."
+	cursorKeyInfo = "middlewarex cursor"
+)
+
+// NewCursorCodec returns a CursorCodec using the given 32 bytes key (e.g. the PASETO key).
+// A zero ttl uses DefaultCursorTTL.
+func NewCursorCodec(key []byte, ttl time.Duration) (*CursorCodec, error) {
+	if err := checkPASETOKey("cursor key", key); err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		ttl = DefaultCursorTTL
+	}
+
+	key, err := hkdf.Key(sha256.New, key, nil, cursorKeyInfo, len(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return &CursorCodec{
+		key: key,
+		ttl: ttl,
+	}, nil
+}
+
+// Seal returns the opaque cursor of the given state for the given query.
+// The query hash and the expiration time of the state are set by the codec.
+func (cc *CursorCodec) Seal(state CursorState, q ListQuery) (string, error) {
+	state.QueryHash = ListQueryHash(q)
+	state.ExpiresAt = time.Now().Add(cc.ttl)
+
+	cursor, err := paseto.Encrypt(cc.key, state, "")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(cursor, cursorHeader), nil
+}
+
+// Open returns the state sealed in the given cursor.
+// It returns a "400 - Bad Request" error wrapping ErrInvalidCursor when the cursor
+// has been tampered with, has expired or belongs to another query.
+func (cc *CursorCodec) Open(cursor string, q ListQuery) (CursorState, error) {
+	var state CursorState
+	if err := paseto.Decrypt(cursorHeader+cursor, cc.key, &state, nil); err != nil {
+		return state, invalidCursor("malformed cursor")
+	}
+
+	if time.Now().After(state.ExpiresAt) {
+		return state, invalidCursor("expired cursor")
+	}
+	if state.QueryHash != ListQueryHash(q) {
+		return state, invalidCursor("cursor belongs to another query")
+	}
+	return state, nil
+}
+
+// ListQueryHash returns a hash of the sort and filter of the given query.
+// Pagination parameters are not part of the hash.
+func ListQueryHash(q ListQuery) string {
+	h := sha256.New()
+	for _, s := range q.Sort {
+		h.Write([]byte(strconv.FormatBool(s.Desc) + ":" + s.Field + "\x00"))
+	}
+	h.Write([]byte{0})
+
+	fields := make([]string, 0, len(q.Filter))
+	for field := range q.Filter {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	for _, field := range fields {
+		h.Write([]byte(field + "\x00" + q.Filter[field] + "\x00"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func invalidCursor(message string) error {
+	return echo.HTTPError{
+		Code:    http.StatusBadRequest,
+		Message: message,
+	}.Wrap(ErrInvalidCursor)
+}
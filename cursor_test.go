@@ -0,0 +1,112 @@
+package middlewarex_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursorCodec(t *testing.T) {
+	_, err := middlewarex.NewCursorCodec([]byte("too short"), 0)
+	assert.EqualError(t, err, "paseto: cursor key must be 32 bytes length, got 9 bytes")
+
+	codec, err := middlewarex.NewCursorCodec([]byte("YELLOW SUBMARINE, BLACK WIZARDRY"), time.Minute)
+	assert.NoError(t, err)
+
+	q := middlewarex.ListQuery{
+		Limit:  10,
+		Sort:   []middlewarex.SortField{{Field: "year", Desc: true}},
+		Filter: map[string]string{"artist": "Miles Davis"},
+	}
+	cursor, err := codec.Seal(middlewarex.CursorState{SortKey: "1970", LastID: "3", Offset: 10}, q)
+	assert.NoError(t, err)
+	assert.NotContains(t, cursor, "v2.local.")
+
+	state, err := codec.Open(cursor, q)
+	assert.NoError(t, err)
+	assert.Equal(t, "1970", state.SortKey)
+	assert.Equal(t, "3", state.LastID)
+	assert.Equal(t, 10, state.Offset)
+
+	// The limit is not part of the query identity
+	q.Limit = 20
+	_, err = codec.Open(cursor, q)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		cursor string
+		query  middlewarex.ListQuery
+	}{
+		{name: "tampered", cursor: cursor[:len(cursor)-2] + "AA", query: q},
+		{name: "garbage", cursor: "10", query: q},
+		{name: "other sort", cursor: cursor, query: middlewarex.ListQuery{Sort: q.Sort}},
+		{name: "other filter", cursor: cursor, query: middlewarex.ListQuery{Filter: map[string]string{"artist": "John Coltrane"}, Sort: q.Sort}},
+	}
+
+	for _, test := range tests {
+		_, err := codec.Open(test.cursor, test.query)
+		assert.ErrorIs(t, err, middlewarex.ErrInvalidCursor, test.name)
+		assert.Equal(t, http.StatusBadRequest, echo.StatusCode(err), test.name)
+	}
+
+	expired, err := middlewarex.NewCursorCodec([]byte("YELLOW SUBMARINE, BLACK WIZARDRY"), time.Nanosecond)
+	assert.NoError(t, err)
+	cursor, err = expired.Seal(middlewarex.CursorState{Offset: 10}, q)
+	assert.NoError(t, err)
+	_, err = expired.Open(cursor, q)
+	var herr *echo.HTTPError
+	if assert.True(t, errors.As(err, &herr)) {
+		assert.Equal(t, "expired cursor", herr.Message)
+	}
+
+	// Cursors are not bearer tokens of the key they are sealed with
+	verifier, err := middlewarex.NewPASETOVerifier(middlewarex.PASETOConfig{SigningKey: []byte("YELLOW SUBMARINE, BLACK WIZARDRY")})
+	assert.NoError(t, err)
+	cursor, err = codec.Seal(middlewarex.CursorState{SortKey: "1970", LastID: "3"}, q)
+	assert.NoError(t, err)
+	_, err = verifier.VerifyToken("v2.local." + cursor)
+	assert.Equal(t, http.StatusUnauthorized, echo.StatusCode(err))
+}
+
+func TestCRUDOfEncryptedCursors(t *testing.T) {
+	ctx := context.Background()
+	codec, err := middlewarex.NewCursorCodec([]byte("YELLOW SUBMARINE, BLACK WIZARDRY"), 0)
+	assert.NoError(t, err)
+
+	repository := middlewarex.NewMemoryRepositoryWithConfig[album](middlewarex.MemoryRepositoryConfig[int]{Cursors: codec})
+	for _, a := range []album{
+		{Title: "Kind of Blue", Artist: "Miles Davis", Year: 1959},
+		{Title: "A Love Supreme", Artist: "John Coltrane", Year: 1965},
+		{Title: "Bitches Brew", Artist: "Miles Davis", Year: 1970},
+	} {
+		assert.NoError(t, repository.Create(ctx, &a))
+	}
+
+	e := echo.New()
+	config := middlewarex.TypedCRUDConfig[album, int]{}
+	config.ListQuery = &middlewarex.ListQueryConfig{}
+	middlewarex.CRUDOfWithConfig(e.Group(""), "/albums", repository, config)
+
+	rec := serve(e, http.MethodGet, "/albums?filter[artist]=Miles%20Davis&sort=year&limit=1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	cursor := rec.Header().Get(middlewarex.XNextCursor)
+	assert.NotEqual(t, "1", cursor)
+
+	rec = serve(e, http.MethodGet, "/albums?filter[artist]=Miles%20Davis&sort=year&limit=1&cursor="+cursor, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id":3,"title":"Bitches Brew","artist":"Miles Davis","year":1970,"version":1}]`, rec.Body.String())
+
+	rec = serve(e, http.MethodGet, "/albums?sort=year&limit=1&cursor="+cursor, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "cursor belongs to another query")
+
+	rec = serve(e, http.MethodGet, "/albums?limit=1&cursor=1", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,272 @@
+package middlewarex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+type (
+	// ListQueryConfig defines the config for ListQuery parsing.
+	ListQueryConfig struct {
+		// DefaultLimit is the number of items per page when not specified.
+		// Optional. Default value 25.
+		DefaultLimit int
+
+		// MaxLimit is the maximum number of items per page, greater limits are capped.
+		// Optional. Default value 100.
+		MaxLimit int
+
+		// SortFields is the list of fields allowed in `?sort=`.
+		// Optional. Default value allows all fields.
+		SortFields []string
+
+		// FilterFields is the list of fields allowed in `?filter[field]=`.
+		// Optional. Default value allows all fields.
+		FilterFields []string
+	}
+
+	// ListQuery is the pagination, sorting and filtering of a List request.
+	//
+	// It is parsed from the following query parameters:
+	//   - `?page=2&per_page=50` for page based pagination
+	//   - `?cursor=xxx&limit=50` for cursor based pagination
+	//   - `?sort=-created_at,name` for sorting (`-` for descending order)
+	//   - `?filter[field]=value` for filtering
+	ListQuery struct {
+		// Page is the requested page number (starting at 1) or 0 for cursor based pagination.
+		Page int
+		// Limit is the number of items per page.
+		Limit int
+		// Cursor is the cursor of cursor based pagination, empty for the first page.
+		Cursor string
+		// Sort is the list of sort criteria.
+		Sort []SortField
+		// Filter is the field/value list of equality filters.
+		Filter map[string]string
+	}
+
+	// ListResult describes the page returned for a ListQuery.
+	ListResult struct {
+		// Total is the total number of items or -1 when unknown.
+		Total int
+		// NextCursor is the cursor of the next page for cursor based pagination, empty on the last page.
+		NextCursor string
+	}
+
+	listQueryContextKey struct{}
+)
+
+const (
+	// XTotalCount is the header for the total number of items of a List response.
+	XTotalCount = "X-Total-Count"
+	// XNextCursor is the header for the cursor of the next page of a List response.
+	XNextCursor = "X-Next-Cursor"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// DefaultListQueryConfig is the default ListQuery config.
+var DefaultListQueryConfig = ListQueryConfig{
+	DefaultLimit: 25,
+	MaxLimit:     100,
+}
+
+// ParseListQuery parses the ListQuery of the given request.
+// It returns a "400 - Bad Request" error for malformed or not allowed parameters.
+func ParseListQuery(c *echo.Context, config ListQueryConfig) (ListQuery, error) {
+	// Defaults
+	if config.DefaultLimit <= 0 {
+		config.DefaultLimit = DefaultListQueryConfig.DefaultLimit
+	}
+	if config.MaxLimit <= 0 {
+		config.MaxLimit = DefaultListQueryConfig.MaxLimit
+	}
+
+	params := c.QueryParams()
+	q := ListQuery{
+		Page:   1,
+		Limit:  config.DefaultLimit,
+		Filter: map[string]string{},
+	}
+
+	cursor := params.Has("cursor") || params.Has("limit")
+	if cursor && (params.Has("page") || params.Has("per_page")) {
+		return q, echo.NewHTTPError(http.StatusBadRequest, "page and cursor based paginations are mutually exclusive")
+	}
+
+	var err error
+	if cursor {
+		q.Page = 0
+		q.Cursor = params.Get("cursor")
+		if q.Limit, err = listQueryInt(params, "limit", config.DefaultLimit); err != nil {
+			return q, err
+		}
+	} else {
+		if q.Page, err = listQueryInt(params, "page", 1); err != nil {
+			return q, err
+		}
+		if q.Limit, err = listQueryInt(params, "per_page", config.DefaultLimit); err != nil {
+			return q, err
+		}
+	}
+	q.Limit = min(q.Limit, config.MaxLimit)
+
+	if sort := params.Get("sort"); sort != "" {
+		for field := range strings.SplitSeq(sort, ",") {
+			s := SortField{Field: strings.TrimSpace(field)}
+			if strings.HasPrefix(s.Field, "-") {
+				s.Field = s.Field[1:]
+				s.Desc = true
+			}
+			if s.Field == "" {
+				return q, echo.NewHTTPError(http.StatusBadRequest, "invalid sort: "+sort)
+			}
+			if len(config.SortFields) > 0 && !slices.Contains(config.SortFields, s.Field) {
+				return q, echo.NewHTTPError(http.StatusBadRequest, "sort is not allowed on field "+s.Field)
+			}
+			q.Sort = append(q.Sort, s)
+		}
+	}
+
+	for key, values := range params {
+		if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+
+		field := key[len("filter[") : len(key)-1]
+		if field == "" {
+			return q, echo.NewHTTPError(http.StatusBadRequest, "invalid filter: "+key)
+		}
+		if len(config.FilterFields) > 0 && !slices.Contains(config.FilterFields, field) {
+			return q, echo.NewHTTPError(http.StatusBadRequest, "filter is not allowed on field "+field)
+		}
+		q.Filter[field] = values[0]
+	}
+
+	return q, nil
+}
+
+// Offset returns the number of items skipped by page based pagination.
+func (q ListQuery) Offset() int {
+	if q.Page <= 0 {
+		return 0
+	}
+	return (q.Page - 1) * q.Limit
+}
+
+// ListQueryFrom returns the ListQuery parsed by CRUD for the List handler.
+func ListQueryFrom(c *echo.Context) (ListQuery, bool) {
+	return ListQueryFromContext(c.Request().Context())
+}
+
+// ListQueryFromContext returns the ListQuery parsed by CRUD from the request's context.Context (e.g. in a Repository).
+func ListQueryFromContext(ctx context.Context) (ListQuery, bool) {
+	q, ok := ctx.Value(listQueryContextKey{}).(ListQuery)
+	return q, ok
+}
+
+// ContextWithListQuery returns a copy of ctx holding the given ListQuery.
+func ContextWithListQuery(ctx context.Context, q ListQuery) context.Context {
+	return context.WithValue(ctx, listQueryContextKey{}, q)
+}
+
+// SetListHeaders sets the `Link' (RFC 8288), `X-Total-Count' and `X-Next-Cursor' headers of a List response.
+func SetListHeaders(c *echo.Context, q ListQuery, result ListResult) {
+	h := c.Response().Header()
+	if result.Total >= 0 {
+		h.Set(XTotalCount, strconv.Itoa(result.Total))
+	}
+	if q.Page == 0 && result.NextCursor != "" {
+		h.Set(XNextCursor, result.NextCursor)
+	}
+
+	links := listLinks(c, q, result)
+	if len(links) == 0 {
+		return
+	}
+
+	values := make([]string, len(links))
+	for i, link := range links {
+		values[i] = fmt.Sprintf(`<%s>; rel="%s"`, link.url, link.rel)
+	}
+	h.Set("Link", strings.Join(values, ", "))
+}
+
+type listLink struct {
+	rel string
+	url string
+}
+
+// listLinks returns the first, prev, next and last pagination links of a List response.
+func listLinks(c *echo.Context, q ListQuery, result ListResult) []listLink {
+	u := *c.Request().URL
+
+	link := func(rel string, params map[string]string) listLink {
+		query := u.Query()
+		for k, v := range params {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+		return listLink{rel: rel, url: u.String()}
+	}
+
+	var links []listLink
+	if q.Page > 0 {
+		limit := strconv.Itoa(q.Limit)
+		last := 1
+		if result.Total > 0 {
+			last = (result.Total + q.Limit - 1) / q.Limit
+		}
+
+		links = append(links, link("first", map[string]string{"page": "1", "per_page": limit}))
+		if q.Page > 1 {
+			links = append(links, link("prev", map[string]string{"page": strconv.Itoa(q.Page - 1), "per_page": limit}))
+		}
+		if result.Total < 0 || q.Page < last {
+			links = append(links, link("next", map[string]string{"page": strconv.Itoa(q.Page + 1), "per_page": limit}))
+		}
+		if result.Total >= 0 {
+			links = append(links, link("last", map[string]string{"page": strconv.Itoa(last), "per_page": limit}))
+		}
+	} else if result.NextCursor != "" {
+		links = append(links, link("next", map[string]string{"cursor": result.NextCursor, "limit": strconv.Itoa(q.Limit)}))
+	}
+	return links
+}
+
+// withListQuery returns a middleware parsing the ListQuery of List requests.
+func withListQuery(config ListQueryConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			q, err := ParseListQuery(c, config)
+			if err != nil {
+				return err
+			}
+
+			r := c.Request()
+			c.SetRequest(r.WithContext(ContextWithListQuery(r.Context(), q)))
+			return next(c)
+		}
+	}
+}
+
+func listQueryInt(params url.Values, name string, value int) (int, error) {
+	if !params.Has(name) {
+		return value, nil
+	}
+
+	v, err := strconv.Atoi(params.Get(name))
+	if err != nil || v < 1 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s must be a positive integer", name))
+	}
+	return v, nil
+}
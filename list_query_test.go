@@ -0,0 +1,198 @@
+package middlewarex_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseListQuery(t *testing.T) {
+	config := middlewarex.ListQueryConfig{
+		DefaultLimit: 10,
+		MaxLimit:     50,
+		SortFields:   []string{"created_at", "name"},
+		FilterFields: []string{"status"},
+	}
+
+	tests := []struct {
+		query      string
+		expected   middlewarex.ListQuery
+		expErrCode int // 0 for Success
+	}{
+		{
+			query:    "",
+			expected: middlewarex.ListQuery{Page: 1, Limit: 10, Filter: map[string]string{}},
+		},
+		{
+			query:    "page=3&per_page=20",
+			expected: middlewarex.ListQuery{Page: 3, Limit: 20, Filter: map[string]string{}},
+		},
+		{
+			query:    "per_page=500",
+			expected: middlewarex.ListQuery{Page: 1, Limit: 50, Filter: map[string]string{}},
+		},
+		{
+			query:    "cursor=abc&limit=5",
+			expected: middlewarex.ListQuery{Limit: 5, Cursor: "abc", Filter: map[string]string{}},
+		},
+		{
+			query:    "limit=5",
+			expected: middlewarex.ListQuery{Limit: 5, Filter: map[string]string{}},
+		},
+		{
+			query: "sort=-created_at,name&filter[status]=active",
+			expected: middlewarex.ListQuery{
+				Page:   1,
+				Limit:  10,
+				Sort:   []middlewarex.SortField{{Field: "created_at", Desc: true}, {Field: "name"}},
+				Filter: map[string]string{"status": "active"},
+			},
+		},
+		{query: "page=0", expErrCode: http.StatusBadRequest},
+		{query: "per_page=ten", expErrCode: http.StatusBadRequest},
+		{query: "page=2&cursor=abc", expErrCode: http.StatusBadRequest},
+		{query: "sort=-", expErrCode: http.StatusBadRequest},
+		{query: "sort=password", expErrCode: http.StatusBadRequest},
+		{query: "filter[password]=secret", expErrCode: http.StatusBadRequest},
+		{query: "filter[]=secret", expErrCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/?"+test.query, nil), httptest.NewRecorder())
+		q, err := middlewarex.ParseListQuery(c, config)
+
+		if test.expErrCode != 0 {
+			if assert.Error(t, err, test.query) {
+				assert.Equal(t, test.expErrCode, err.(*echo.HTTPError).Code, test.query)
+			}
+			continue
+		}
+
+		assert.NoError(t, err, test.query)
+		assert.Equal(t, test.expected, q, test.query)
+	}
+}
+
+func TestSetListHeaders(t *testing.T) {
+	tests := []struct {
+		query  string
+		result middlewarex.ListResult
+		link   string
+		total  string
+		cursor string
+	}{
+		{
+			query:  "page=2&per_page=10&sort=name",
+			result: middlewarex.ListResult{Total: 35},
+			link: `</albums?page=1&per_page=10&sort=name>; rel="first", ` +
+				`</albums?page=1&per_page=10&sort=name>; rel="prev", ` +
+				`</albums?page=3&per_page=10&sort=name>; rel="next", ` +
+				`</albums?page=4&per_page=10&sort=name>; rel="last"`,
+			total: "35",
+		},
+		{
+			query:  "page=1&per_page=10",
+			result: middlewarex.ListResult{Total: -1},
+			link:   `</albums?page=1&per_page=10>; rel="first", </albums?page=2&per_page=10>; rel="next"`,
+		},
+		{
+			query:  "limit=10",
+			result: middlewarex.ListResult{Total: -1, NextCursor: "next"},
+			link:   `</albums?cursor=next&limit=10>; rel="next"`,
+			cursor: "next",
+		},
+		{
+			query:  "cursor=last&limit=10",
+			result: middlewarex.ListResult{Total: 12},
+			total:  "12",
+		},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/albums?"+test.query, nil), rec)
+		q, err := middlewarex.ParseListQuery(c, middlewarex.DefaultListQueryConfig)
+		assert.NoError(t, err)
+
+		middlewarex.SetListHeaders(c, q, test.result)
+		assert.Equal(t, test.link, rec.Header().Get("Link"), test.query)
+		assert.Equal(t, test.total, rec.Header().Get(middlewarex.XTotalCount), test.query)
+		assert.Equal(t, test.cursor, rec.Header().Get(middlewarex.XNextCursor), test.query)
+	}
+}
+
+type crudListQueryCtrl struct{}
+
+func (*crudListQueryCtrl) List(c *echo.Context) error {
+	q, ok := middlewarex.ListQueryFrom(c)
+	if !ok {
+		return c.NoContent(http.StatusNoContent)
+	}
+	return c.JSON(http.StatusOK, q)
+}
+
+func TestCRUDListQuery(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUD(e.Group(""), "/plain", &crudListQueryCtrl{})
+	middlewarex.CRUDWithConfig(e.Group(""), "/tests", &crudListQueryCtrl{}, middlewarex.CRUDConfig{
+		ListQuery: &middlewarex.ListQueryConfig{SortFields: []string{"name"}},
+	})
+
+	rec := serve(e, http.MethodGet, "/plain?sort=name", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = serve(e, http.MethodGet, "/tests?sort=name", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"Page":1,"Limit":25,"Cursor":"","Sort":[{"Field":"name","Desc":false}],"Filter":{}}`, rec.Body.String())
+
+	rec = serve(e, http.MethodGet, "/tests?sort=age", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCRUDOfListQuery(t *testing.T) {
+	ctx := context.Background()
+	repository := middlewarex.NewMemoryRepository[album, int]()
+	for _, a := range []album{
+		{Title: "Kind of Blue", Artist: "Miles Davis", Year: 1959},
+		{Title: "A Love Supreme", Artist: "John Coltrane", Year: 1965},
+		{Title: "Bitches Brew", Artist: "Miles Davis", Year: 1970},
+		{Title: "Giant Steps", Artist: "John Coltrane", Year: 1960},
+	} {
+		assert.NoError(t, repository.Create(ctx, &a))
+	}
+
+	e := echo.New()
+	config := middlewarex.TypedCRUDConfig[album, int]{}
+	config.ListQuery = &middlewarex.ListQueryConfig{DefaultLimit: 2}
+	middlewarex.CRUDOfWithConfig(e.Group(""), "/albums", repository, config)
+
+	rec := serve(e, http.MethodGet, "/albums?sort=-year&page=2", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "4", rec.Header().Get(middlewarex.XTotalCount))
+	assert.JSONEq(t, `[
+		{"id":4,"title":"Giant Steps","artist":"John Coltrane","year":1960,"version":1},
+		{"id":1,"title":"Kind of Blue","artist":"Miles Davis","year":1959,"version":1}
+	]`, rec.Body.String())
+
+	rec = serve(e, http.MethodGet, "/albums?filter[artist]=Miles%20Davis&sort=year&limit=1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id":1,"title":"Kind of Blue","artist":"Miles Davis","year":1959,"version":1}]`, rec.Body.String())
+	cursor := rec.Header().Get(middlewarex.XNextCursor)
+	assert.NotEmpty(t, cursor)
+
+	rec = serve(e, http.MethodGet, "/albums?filter[artist]=Miles%20Davis&sort=year&limit=1&cursor="+cursor, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id":3,"title":"Bitches Brew","artist":"Miles Davis","year":1970,"version":1}]`, rec.Body.String())
+	assert.Empty(t, rec.Header().Get(middlewarex.XNextCursor))
+
+	rec = serve(e, http.MethodGet, "/albums?limit=1&cursor=garbage", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(e, http.MethodGet, "/albums?sort=label", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
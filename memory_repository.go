@@ -0,0 +1,482 @@
+package middlewarex
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// MemoryRepositoryConfig defines the config for MemoryRepository.
+	MemoryRepositoryConfig[ID comparable] struct {
+		// NewID generates the ID of the created values.
+		// Optional. Default value generates sequential IDs for integer IDs and random UUIDs for string IDs.
+		NewID func() ID
+
+		// Cursors seals the cursors of ListPage into opaque encrypted cursors.
+		// Optional. Default value uses plain offsets as cursors.
+		Cursors *CursorCodec
+	}
+
+	// MemoryRepository is a concurrency-safe in-memory Repository.
+	// It is meant for prototypes and tests.
+	//
+	// T must be a struct with an ID field, named `ID` or tagged `crud:"id"`.
+	// When T has a version field, named `Version` or tagged `crud:"version"`, it is used for optimistic versioning:
+	// Create sets it to 1 and Update increments it but fails with ErrConflict if the given version is not the stored one.
+	// A zero version skips the check.
+	MemoryRepository[T any, ID comparable] struct {
+		mu       sync.RWMutex
+		values   map[ID]T
+		order    []ID
+		sequence uint64
+		newID    func() ID
+		cursors  *CursorCodec
+		fields   map[string][]int
+		id       []int
+		version  []int
+	}
+
+	// SortField defines a sort criterion on a field.
+	SortField struct {
+		Field string
+		Desc  bool
+	}
+)
+
+// Errors
+var (
+	ErrConflict     = errors.New("conflict")
+	ErrUnknownField = errors.New("unknown field")
+)
+
+// NewMemoryRepository returns a new MemoryRepository.
+// It panics if T is not a struct with an ID field of type ID.
+func NewMemoryRepository[T any, ID comparable]() *MemoryRepository[T, ID] {
+	return NewMemoryRepositoryWithConfig[T](MemoryRepositoryConfig[ID]{})
+}
+
+// NewMemoryRepositoryWithConfig returns a new MemoryRepository with config.
+// It panics if T is not a struct with an ID field of type ID.
+func NewMemoryRepositoryWithConfig[T any, ID comparable](config MemoryRepositoryConfig[ID]) *MemoryRepository[T, ID] {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		panic("MemoryRepository only supports structs, got " + t.String())
+	}
+
+	r := &MemoryRepository[T, ID]{
+		values:  map[ID]T{},
+		newID:   config.NewID,
+		cursors: config.Cursors,
+		fields:  map[string][]int{},
+	}
+
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		switch tag := field.Tag.Get("crud"); {
+		case tag == "id" || (tag == "" && field.Name == "ID" && r.id == nil):
+			r.id = field.Index
+		case tag == "version" || (tag == "" && field.Name == "Version" && r.version == nil):
+			r.version = field.Index
+		}
+
+		// The fields hidden from JSON cannot be filtered nor sorted
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+		name := field.Name
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" {
+			name = tag
+		}
+		r.fields[strings.ToLower(name)] = field.Index
+	}
+
+	if r.id == nil {
+		panic("MemoryRepository requires an ID field in " + t.String())
+	}
+	if idt := reflect.TypeFor[ID](); t.FieldByIndex(r.id).Type != idt {
+		panic(fmt.Sprintf("MemoryRepository ID field of %s must be a %s", t, idt))
+	}
+	if r.version != nil && !isInteger(t.FieldByIndex(r.version).Type.Kind()) {
+		panic("MemoryRepository version field of " + t.String() + " must be an integer")
+	}
+
+	if r.newID == nil {
+		r.newID = r.defaultNewID()
+	}
+
+	return r
+}
+
+// Create stores the given value with a generated ID.
+func (r *MemoryRepository[T, ID]) Create(_ context.Context, v *T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.newID()
+	if _, ok := r.values[id]; ok {
+		return fmt.Errorf("id %v: %w", id, ErrConflict)
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	rv.FieldByIndex(r.id).Set(reflect.ValueOf(id))
+	if r.version != nil {
+		rv.FieldByIndex(r.version).SetInt(1)
+	}
+
+	r.values[id] = *v
+	r.order = append(r.order, id)
+	return nil
+}
+
+// List returns all the values in their creation order.
+func (r *MemoryRepository[T, ID]) List(ctx context.Context) ([]T, error) {
+	return r.Find(ctx, nil)
+}
+
+// Find returns the values matching all the given filters, sorted by the given fields.
+// Filters and sort fields are addressed by their JSON name (or Go name) and filter values are compared with their string representation.
+// Unknown fields, including the fields tagged `json:"-"', are reported with ErrUnknownField.
+func (r *MemoryRepository[T, ID]) Find(_ context.Context, filter map[string]string, sort ...SortField) ([]T, error) {
+	filters := map[string][]int{}
+	for name := range filter {
+		index, err := r.field(name)
+		if err != nil {
+			return nil, err
+		}
+		filters[name] = index
+	}
+
+	sorts := make([][]int, len(sort))
+	for i, s := range sort {
+		index, err := r.field(s.Field)
+		if err != nil {
+			return nil, err
+		}
+		sorts[i] = index
+	}
+
+	r.mu.RLock()
+	values := make([]T, 0, len(r.order))
+LOOP:
+	for _, id := range r.order {
+		v := r.values[id]
+		rv := reflect.ValueOf(v)
+		for name, index := range filters {
+			if fieldString(rv.FieldByIndex(index)) != filter[name] {
+				continue LOOP
+			}
+		}
+		values = append(values, v)
+	}
+	r.mu.RUnlock()
+
+	if len(sort) > 0 {
+		slices.SortStableFunc(values, func(a, b T) int {
+			ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+			for i, s := range sort {
+				c := compareValues(ra.FieldByIndex(sorts[i]), rb.FieldByIndex(sorts[i]))
+				if s.Desc {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+	}
+
+	return values, nil
+}
+
+// ListPage returns the page of values matching the given ListQuery.
+// Cursors are the offset of the next page, sealed when a CursorCodec is configured.
+// A zero Limit uses DefaultListQueryConfig.DefaultLimit.
+func (r *MemoryRepository[T, ID]) ListPage(ctx context.Context, q ListQuery) ([]T, ListResult, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultListQueryConfig.DefaultLimit
+	}
+
+	values, err := r.Find(ctx, q.Filter, q.Sort...)
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	offset := q.Offset()
+	if q.Page == 0 && q.Cursor != "" {
+		if offset, err = r.openCursor(q); err != nil {
+			return nil, ListResult{}, err
+		}
+	}
+
+	result := ListResult{Total: len(values)}
+	start := min(offset, len(values))
+	end := min(start+q.Limit, len(values))
+	if q.Page == 0 && end < len(values) {
+		if result.NextCursor, err = r.sealCursor(q, values[end-1], end); err != nil {
+			return nil, ListResult{}, err
+		}
+	}
+
+	return values[start:end], result, nil
+}
+
+func (r *MemoryRepository[T, ID]) openCursor(q ListQuery) (int, error) {
+	if r.cursors == nil {
+		offset, err := strconv.Atoi(q.Cursor)
+		if err != nil || offset < 0 {
+			return 0, ErrInvalidCursor
+		}
+		return offset, nil
+	}
+
+	state, err := r.cursors.Open(q.Cursor, q)
+	if err != nil {
+		return 0, err
+	}
+	return state.Offset, nil
+}
+
+func (r *MemoryRepository[T, ID]) sealCursor(q ListQuery, last T, offset int) (string, error) {
+	if r.cursors == nil {
+		return strconv.Itoa(offset), nil
+	}
+
+	v := reflect.ValueOf(last)
+	state := CursorState{
+		LastID: fieldString(v.FieldByIndex(r.id)),
+		Offset: offset,
+	}
+	if len(q.Sort) > 0 {
+		index, err := r.field(q.Sort[0].Field)
+		if err != nil {
+			return "", err
+		}
+		state.SortKey = fieldString(v.FieldByIndex(index))
+	}
+	return r.cursors.Seal(state, q)
+}
+
+// Get returns the value identified by id or ErrNotFound.
+func (r *MemoryRepository[T, ID]) Get(_ context.Context, id ID) (T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, ok := r.values[id]
+	if !ok {
+		return v, ErrNotFound
+	}
+	return v, nil
+}
+
+// Update replaces the value identified by id.
+// It returns ErrNotFound if the value does not exist and ErrConflict if the version mismatches.
+func (r *MemoryRepository[T, ID]) Update(_ context.Context, id ID, v *T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.values[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	rv.FieldByIndex(r.id).Set(reflect.ValueOf(id))
+	if r.version != nil {
+		stored := reflect.ValueOf(current).FieldByIndex(r.version).Int()
+		version := rv.FieldByIndex(r.version)
+		if version.Int() != 0 && version.Int() != stored {
+			return fmt.Errorf("version %d does not match the stored version %d: %w", version.Int(), stored, ErrConflict)
+		}
+		version.SetInt(stored + 1)
+	}
+
+	r.values[id] = *v
+	return nil
+}
+
+// Delete removes the value identified by id or returns ErrNotFound.
+func (r *MemoryRepository[T, ID]) Delete(_ context.Context, id ID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.values[id]; !ok {
+		return ErrNotFound
+	}
+
+	delete(r.values, id)
+	r.order = slices.DeleteFunc(r.order, func(v ID) bool { return v == id })
+	return nil
+}
+
+// Snapshot writes all the values as a JSON array.
+func (r *MemoryRepository[T, ID]) Snapshot(w io.Writer) error {
+	values, err := r.List(context.Background())
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(values)
+}
+
+// Restore replaces all the values by the ones of the given JSON array (e.g. written by Snapshot).
+func (r *MemoryRepository[T, ID]) Restore(rd io.Reader) error {
+	var values []T
+	if err := json.NewDecoder(rd).Decode(&values); err != nil {
+		return err
+	}
+
+	valuesByID := make(map[ID]T, len(values))
+	order := make([]ID, 0, len(values))
+	var sequence uint64
+	for _, v := range values {
+		rid := reflect.ValueOf(v).FieldByIndex(r.id)
+		id := rid.Interface().(ID)
+		if _, ok := valuesByID[id]; ok {
+			return fmt.Errorf("duplicated id %v: %w", id, ErrConflict)
+		}
+
+		valuesByID[id] = v
+		order = append(order, id)
+		if isInteger(rid.Kind()) {
+			sequence = max(sequence, uint64(rid.Int()))
+		} else if isUnsigned(rid.Kind()) {
+			sequence = max(sequence, rid.Uint())
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.values = valuesByID
+	r.order = order
+	r.sequence = sequence
+	return nil
+}
+
+// SaveFile writes a snapshot of the repository to the named file.
+func (r *MemoryRepository[T, ID]) SaveFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err = r.Snapshot(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadFile restores the repository from the named file (e.g. fixtures loaded at startup).
+func (r *MemoryRepository[T, ID]) LoadFile(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return r.Restore(f)
+}
+
+func (r *MemoryRepository[T, ID]) field(name string) ([]int, error) {
+	index, ok := r.fields[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownField, name)
+	}
+	return index, nil
+}
+
+// defaultNewID returns a sequential ID generator for integer IDs and a random UUID generator for string IDs.
+// It is called with the lock held.
+func (r *MemoryRepository[T, ID]) defaultNewID() func() ID {
+	t := reflect.TypeFor[ID]()
+	switch {
+	case isInteger(t.Kind()), isUnsigned(t.Kind()):
+		return func() ID {
+			r.sequence++
+			id := reflect.New(t).Elem()
+			if isInteger(t.Kind()) {
+				id.SetInt(int64(r.sequence))
+			} else {
+				id.SetUint(r.sequence)
+			}
+			return id.Interface().(ID)
+		}
+	case t.Kind() == reflect.String:
+		return func() ID {
+			return reflect.ValueOf(newUUID()).Convert(t).Interface().(ID)
+		}
+	default:
+		panic("MemoryRepository requires a NewID function for " + t.String() + " IDs")
+	}
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:]) //nolint:errcheck // never returns an error
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func compareValues(a, b reflect.Value) int {
+	switch {
+	case isInteger(a.Kind()):
+		return cmp.Compare(a.Int(), b.Int())
+	case isUnsigned(a.Kind()):
+		return cmp.Compare(a.Uint(), b.Uint())
+	case a.Kind() == reflect.Float32 || a.Kind() == reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case a.Kind() == reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	case a.Kind() == reflect.Bool:
+		return cmp.Compare(boolInt(a.Bool()), boolInt(b.Bool()))
+	}
+
+	if ta, ok := a.Interface().(time.Time); ok {
+		return ta.Compare(b.Interface().(time.Time))
+	}
+	return cmp.Compare(fieldString(a), fieldString(b))
+}
+
+// fieldString returns the string representation of a field used by filters.
+func fieldString(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func isInteger(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uint64
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,218 @@
+package middlewarex_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+type album struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Artist  string `json:"artist"`
+	Year    int    `json:"year"`
+	Version int    `json:"version"`
+}
+
+type note struct {
+	Key  string `json:"key" crud:"id"`
+	Body string `json:"body"`
+}
+
+func TestMemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	repository := middlewarex.NewMemoryRepository[album, int]()
+
+	for _, a := range []album{
+		{Title: "Kind of Blue", Artist: "Miles Davis", Year: 1959},
+		{Title: "A Love Supreme", Artist: "John Coltrane", Year: 1965},
+		{Title: "Bitches Brew", Artist: "Miles Davis", Year: 1970},
+	} {
+		assert.NoError(t, repository.Create(ctx, &a))
+	}
+
+	a, err := repository.Get(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, album{ID: 2, Title: "A Love Supreme", Artist: "John Coltrane", Year: 1965, Version: 1}, a)
+
+	_, err = repository.Get(ctx, 42)
+	assert.ErrorIs(t, err, middlewarex.ErrNotFound)
+
+	// Filtering and sorting
+	albums, err := repository.Find(ctx, map[string]string{"artist": "Miles Davis"}, middlewarex.SortField{Field: "year", Desc: true})
+	assert.NoError(t, err)
+	if assert.Len(t, albums, 2) {
+		assert.Equal(t, "Bitches Brew", albums[0].Title)
+		assert.Equal(t, "Kind of Blue", albums[1].Title)
+	}
+
+	albums, err = repository.Find(ctx, map[string]string{"Year": "1965"})
+	assert.NoError(t, err)
+	assert.Len(t, albums, 1)
+
+	_, err = repository.Find(ctx, nil, middlewarex.SortField{Field: "label"})
+	assert.ErrorIs(t, err, middlewarex.ErrUnknownField)
+
+	// Zero ListQuery uses the default limit
+	albums, result, err := repository.ListPage(ctx, middlewarex.ListQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, albums, 3)
+	assert.Equal(t, 3, result.Total)
+	assert.Empty(t, result.NextCursor)
+
+	// Optimistic versioning
+	a.Year = 1964
+	assert.NoError(t, repository.Update(ctx, 2, &a))
+	assert.Equal(t, 2, a.Version)
+
+	a.Version = 1
+	assert.ErrorIs(t, repository.Update(ctx, 2, &a), middlewarex.ErrConflict)
+
+	a.Version = 0
+	assert.NoError(t, repository.Update(ctx, 2, &a))
+	assert.Equal(t, 3, a.Version)
+
+	assert.ErrorIs(t, repository.Update(ctx, 42, &a), middlewarex.ErrNotFound)
+
+	// Deletion keeps the order
+	assert.NoError(t, repository.Delete(ctx, 1))
+	assert.ErrorIs(t, repository.Delete(ctx, 1), middlewarex.ErrNotFound)
+	albums, err = repository.List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, albums, 2) {
+		assert.Equal(t, 2, albums[0].ID)
+		assert.Equal(t, 3, albums[1].ID)
+	}
+}
+
+func TestMemoryRepositoryHiddenFields(t *testing.T) {
+	type user struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		Password string `json:"-"`
+	}
+
+	ctx := context.Background()
+	repository := middlewarex.NewMemoryRepository[user, int]()
+	assert.NoError(t, repository.Create(ctx, &user{Name: "alice", Password: "hunter2"}))
+
+	_, err := repository.Find(ctx, map[string]string{"password": "hunter2"})
+	assert.ErrorIs(t, err, middlewarex.ErrUnknownField)
+	_, err = repository.Find(ctx, nil, middlewarex.SortField{Field: "Password"})
+	assert.ErrorIs(t, err, middlewarex.ErrUnknownField)
+
+	e := echo.New()
+	config := middlewarex.TypedCRUDConfig[user, int]{}
+	config.ListQuery = &middlewarex.ListQueryConfig{}
+	middlewarex.CRUDOfWithConfig(e.Group(""), "/users", repository, config)
+	for _, password := range []string{"hunter2", "wrong"} {
+		rec := serve(e, http.MethodGet, "/users?filter[password]="+password, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, password)
+	}
+}
+
+func TestMemoryRepositorySnapshot(t *testing.T) {
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "albums.json")
+
+	repository := middlewarex.NewMemoryRepository[album, int]()
+	assert.NoError(t, repository.Create(ctx, &album{Title: "Kind of Blue"}))
+	assert.NoError(t, repository.Create(ctx, &album{Title: "A Love Supreme"}))
+	assert.NoError(t, repository.SaveFile(filename))
+
+	restored := middlewarex.NewMemoryRepository[album, int]()
+	assert.NoError(t, restored.LoadFile(filename))
+
+	albums, err := restored.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, albums, 2)
+
+	// The ID sequence continues after the restored IDs
+	a := album{Title: "Bitches Brew"}
+	assert.NoError(t, restored.Create(ctx, &a))
+	assert.Equal(t, 3, a.ID)
+
+	err = restored.Restore(bytes.NewBufferString(`[{"id":1},{"id":1}]`))
+	assert.ErrorIs(t, err, middlewarex.ErrConflict)
+	albums, err = restored.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, albums, 3, "a failed restore must not alter the repository")
+}
+
+func TestMemoryRepositoryIDs(t *testing.T) {
+	ctx := context.Background()
+
+	notes := middlewarex.NewMemoryRepository[note, string]()
+	n := note{Body: "hello"}
+	assert.NoError(t, notes.Create(ctx, &n))
+	assert.NoError(t, middlewarex.ValidateUUID(n.Key))
+
+	next := "a"
+	custom := middlewarex.NewMemoryRepositoryWithConfig[note](middlewarex.MemoryRepositoryConfig[string]{
+		NewID: func() string {
+			next += "a"
+			return next
+		},
+	})
+	n = note{Body: "hello"}
+	assert.NoError(t, custom.Create(ctx, &n))
+	assert.Equal(t, "aa", n.Key)
+
+	assert.Panics(t, func() { middlewarex.NewMemoryRepository[album, string]() }, "ID type mismatch")
+	assert.Panics(t, func() { middlewarex.NewMemoryRepository[struct{ Name string }, int]() }, "missing ID field")
+}
+
+func TestMemoryRepositoryConcurrency(t *testing.T) {
+	ctx := context.Background()
+	repository := middlewarex.NewMemoryRepository[album, int]()
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			a := album{Title: "Kind of Blue"}
+			if err := repository.Create(ctx, &a); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := repository.List(ctx); err != nil {
+				t.Error(err)
+			}
+			if err := repository.Update(ctx, a.ID, &a); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	albums, err := repository.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, albums, 50)
+}
+
+func TestCRUDOfMemoryRepository(t *testing.T) {
+	e := echo.New()
+	middlewarex.CRUDOf(e.Group(""), "/albums", middlewarex.NewMemoryRepository[album, int]())
+
+	rec := serve(e, http.MethodPost, "/albums", `{"title":"Kind of Blue","artist":"Miles Davis"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"id":1,"title":"Kind of Blue","artist":"Miles Davis","year":0,"version":1}`, rec.Body.String())
+
+	rec = serve(e, http.MethodPatch, "/albums/1", `{"year":1959}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":1,"title":"Kind of Blue","artist":"Miles Davis","year":1959,"version":2}`, rec.Body.String())
+
+	rec = serve(e, http.MethodPatch, "/albums/1", `{"year":1960,"version":1}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = serve(e, http.MethodGet, "/albums/2", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.True(t, errors.Is(middlewarex.DefaultTypedMapError(middlewarex.ErrNotFound), middlewarex.ErrNotFound))
+}
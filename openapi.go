@@ -0,0 +1,519 @@
+package middlewarex
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+)
+
+type (
+	// OpenAPIConfig defines the config for OpenAPI document generation.
+	OpenAPIConfig struct {
+		// Title is the title of the API.
+		// Optional. Default value "API".
+		Title string
+
+		// Version is the version of the API.
+		// Optional. Default value "1.0.0", the version header value for versioned documents.
+		Version string
+
+		// Description is the description of the API.
+		// Optional.
+		Description string
+	}
+
+	// OpenAPIDocument is an OpenAPI 3.1 document.
+	OpenAPIDocument struct {
+		OpenAPI    string                     `json:"openapi"`
+		Info       OpenAPIInfo                `json:"info"`
+		Paths      map[string]OpenAPIPathItem `json:"paths"`
+		Components OpenAPIComponents          `json:"components"`
+	}
+
+	// OpenAPIInfo is the metadata of an OpenAPIDocument.
+	OpenAPIInfo struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description,omitempty"`
+	}
+
+	// OpenAPIPathItem is the set of operations of a path, by lowercase HTTP method.
+	OpenAPIPathItem map[string]*OpenAPIOperation
+
+	// OpenAPIOperation describes an API operation.
+	OpenAPIOperation struct {
+		OperationID string                     `json:"operationId"`
+		Tags        []string                   `json:"tags,omitempty"`
+		Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+		Responses   map[string]OpenAPIResponse `json:"responses"`
+	}
+
+	// OpenAPIParameter describes an operation parameter.
+	OpenAPIParameter struct {
+		Name     string     `json:"name"`
+		In       string     `json:"in"`
+		Required bool       `json:"required,omitempty"`
+		Schema   JSONSchema `json:"schema"`
+	}
+
+	// OpenAPIRequestBody describes an operation request body.
+	OpenAPIRequestBody struct {
+		Required bool                        `json:"required"`
+		Content  map[string]OpenAPIMediaType `json:"content"`
+	}
+
+	// OpenAPIResponse describes an operation response.
+	OpenAPIResponse struct {
+		Description string                      `json:"description"`
+		Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+	}
+
+	// OpenAPIMediaType describes the content of a request or a response.
+	OpenAPIMediaType struct {
+		Schema JSONSchema `json:"schema"`
+	}
+
+	// OpenAPIComponents holds the reusable schemas of an OpenAPIDocument.
+	OpenAPIComponents struct {
+		Schemas map[string]JSONSchema `json:"schemas"`
+	}
+
+	// JSONSchema is a JSON Schema (2020-12).
+	JSONSchema map[string]any
+)
+
+// DefaultOpenAPIConfig is the default OpenAPI config.
+var DefaultOpenAPIConfig = OpenAPIConfig{
+	Title:   "API",
+	Version: "1.0.0",
+}
+
+// OpenAPI returns the OpenAPI 3.1 document of the given resources and their nested resources.
+func OpenAPI(config OpenAPIConfig, resources ...*Resource) *OpenAPIDocument {
+	if config.Version == "" {
+		config.Version = DefaultOpenAPIConfig.Version
+	}
+	return openAPI(config, "", "", resources)
+}
+
+// VersionedOpenAPI returns the OpenAPI 3.1 documents of the given resources, one per version supported by the Versioning middleware.
+// Each document holds the routes registered under the version's prefix (e.g. `/v1' for "vnd.github.v1"), without the prefix,
+// and requires the `X-Application-Version' header.
+func VersionedOpenAPI(config OpenAPIConfig, versions []string, resources ...*Resource) map[string]*OpenAPIDocument {
+	documents := map[string]*OpenAPIDocument{}
+	for _, vnd := range versions {
+		c := config
+		if c.Version == "" {
+			c.Version = vnd
+		}
+		documents[vnd] = openAPI(c, vnd2prefix(vnd), vnd, resources)
+	}
+	return documents
+}
+
+// OpenAPIHandler returns a handler rendering the given document as JSON.
+func OpenAPIHandler(document *OpenAPIDocument) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		return c.JSON(http.StatusOK, document)
+	}
+}
+
+func openAPI(config OpenAPIConfig, prefix, vnd string, resources []*Resource) *OpenAPIDocument {
+	// Defaults
+	if config.Title == "" {
+		config.Title = DefaultOpenAPIConfig.Title
+	}
+
+	document := &OpenAPIDocument{
+		OpenAPI: "3.1.0",
+		Info: OpenAPIInfo{
+			Title:       config.Title,
+			Version:     config.Version,
+			Description: config.Description,
+		},
+		Paths:      map[string]OpenAPIPathItem{},
+		Components: OpenAPIComponents{Schemas: map[string]JSONSchema{}},
+	}
+
+	operationIDs := map[string]bool{}
+	var walk func(resources []*Resource)
+	walk = func(resources []*Resource) {
+		for _, resource := range resources {
+			info := resource.Info()
+			for _, route := range info.Routes {
+				path := route.Path
+				if prefix != "" {
+					if !strings.HasPrefix(path, prefix+"/") {
+						continue
+					}
+					path = strings.TrimPrefix(path, prefix)
+				}
+
+				operation := openAPIOperation(info, route, document.Components.Schemas)
+				if operationIDs[operation.OperationID] {
+					operation.OperationID += "." + strings.ToLower(route.Method) // e.g. Update on PATCH and PUT
+				}
+				operationIDs[operation.OperationID] = true
+				if vnd != "" {
+					operation.Parameters = append(operation.Parameters, OpenAPIParameter{
+						Name:     XApplicationVersion,
+						In:       "header",
+						Required: true,
+						Schema:   JSONSchema{"type": "string", "const": vnd},
+					})
+				}
+
+				path = openAPIPath(path)
+				if document.Paths[path] == nil {
+					document.Paths[path] = OpenAPIPathItem{}
+				}
+				document.Paths[path][strings.ToLower(route.Method)] = operation
+			}
+			walk(resource.Children())
+		}
+	}
+	walk(resources)
+
+	return document
+}
+
+func openAPIOperation(info ResourceInfo, route echo.RouteInfo, schemas map[string]JSONSchema) *OpenAPIOperation {
+	verb := VerbAll
+	if i := slices.Index(verbNames, route.Name[strings.LastIndex(route.Name, ".")+1:]); i >= 0 {
+		verb = 1 << i
+	}
+
+	operation := &OpenAPIOperation{
+		OperationID: route.Name,
+		Tags:        []string{info.Name},
+		Responses:   map[string]OpenAPIResponse{},
+	}
+
+	for _, param := range route.Parameters {
+		schema := JSONSchema{"type": "string"}
+		if param == info.IDParam && info.IDType != nil {
+			schema = jsonSchema(info.IDType, schemas)
+		}
+		operation.Parameters = append(operation.Parameters, OpenAPIParameter{
+			Name:     param,
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+
+	var item, items, input JSONSchema
+	if info.Type != nil {
+		if info.Fields != nil && len(info.Fields.Hidden) > 0 {
+			item = visibleSchema(info.Type, hiddenFields(*info.Fields), schemas)
+		} else {
+			item = jsonSchema(info.Type, schemas)
+		}
+		items = JSONSchema{"type": "array", "items": item}
+		input = item
+		if info.JSONAPIType != "" {
+			item, items, input = jsonAPISchemas(info.JSONAPIType, item)
+		}
+	}
+	bulk := JSONSchema{"type": "array", "items": JSONSchema{}}
+	if item != nil {
+		bulk = items
+	}
+
+	switch verb {
+	case VerbCreate:
+		operation.RequestBody = openAPIRequestBody(input)
+		operation.Responses["201"] = openAPIResponse(http.StatusCreated, item)
+	case VerbList:
+		if info.ListQuery != nil {
+			for _, name := range []string{"page", "per_page", "limit"} {
+				operation.Parameters = append(operation.Parameters, OpenAPIParameter{Name: name, In: "query", Schema: JSONSchema{"type": "integer", "minimum": 1}})
+			}
+			for _, name := range []string{"cursor", "sort"} {
+				operation.Parameters = append(operation.Parameters, OpenAPIParameter{Name: name, In: "query", Schema: JSONSchema{"type": "string"}})
+			}
+		}
+		operation.Responses["200"] = openAPIResponse(http.StatusOK, items)
+	case VerbShow:
+		operation.Responses["200"] = openAPIResponse(http.StatusOK, item)
+	case VerbRestore:
+		operation.Responses["200"] = openAPIResponse(http.StatusOK, item)
+	case VerbPurge:
+		operation.Responses["204"] = openAPIResponse(http.StatusNoContent, nil)
+	case VerbUpdate, VerbReplace:
+		operation.RequestBody = openAPIRequestBody(input)
+		if verb == VerbUpdate && item != nil {
+			operation.RequestBody.Content[MIMEApplicationMergePatch] = OpenAPIMediaType{Schema: JSONSchema{"type": "object"}}
+			operation.RequestBody.Content[MIMEApplicationJSONPatch] = OpenAPIMediaType{Schema: jsonPatchSchema}
+		}
+		operation.Responses["200"] = openAPIResponse(http.StatusOK, item)
+	case VerbDelete:
+		operation.Responses["204"] = openAPIResponse(http.StatusNoContent, nil)
+	case VerbBulkCreate, VerbBulkUpdate, VerbBulkDelete:
+		if verb == VerbBulkDelete {
+			operation.Parameters = append(operation.Parameters, OpenAPIParameter{Name: "ids", In: "query", Required: true, Schema: JSONSchema{"type": "string"}})
+		} else {
+			operation.RequestBody = openAPIRequestBody(bulk)
+		}
+		results := jsonSchema(reflect.TypeFor[BulkResponse](), schemas)
+		operation.Responses["200"] = openAPIResponse(http.StatusOK, results)
+		operation.Responses["207"] = openAPIResponse(http.StatusMultiStatus, results)
+	default:
+		operation.Responses["default"] = OpenAPIResponse{Description: "Response"}
+	}
+
+	if info.SoftDelete && (verb == VerbList || verb == VerbShow) {
+		operation.Parameters = append(operation.Parameters, OpenAPIParameter{Name: "include_deleted", In: "query", Schema: JSONSchema{"type": "boolean"}})
+	}
+	if info.Fields != nil && verb&(VerbCreate|VerbList|VerbShow|VerbUpdate|VerbReplace|VerbRestore) != 0 {
+		operation.Parameters = append(operation.Parameters, OpenAPIParameter{Name: "fields", In: "query", Schema: JSONSchema{"type": "string"}})
+	}
+	if info.Type != nil && route.Path == info.Path+"/:"+info.IDParam {
+		operation.Responses["404"] = openAPIResponse(http.StatusNotFound, nil)
+	}
+	if info.JSONAPIType != "" {
+		// The patches apply to the JSON value, the other documents are JSON:API documents
+		if operation.RequestBody != nil {
+			jsonAPIContent(operation.RequestBody.Content)
+		}
+		for _, response := range operation.Responses {
+			jsonAPIContent(response.Content)
+		}
+	}
+	return operation
+}
+
+// jsonAPISchemas returns the JSON Schemas of the JSON:API documents of a resource object (response),
+// of a collection of resource objects (response) and of a resource object without ID (request), the attributes being the given schema.
+func jsonAPISchemas(typ string, attributes JSONSchema) (item, items, input JSONSchema) {
+	object := JSONSchema{
+		"type":     "object",
+		"required": []string{"type", "id"},
+		"properties": JSONSchema{
+			"type":          JSONSchema{"type": "string", "const": typ},
+			"id":            JSONSchema{"type": "string"},
+			"attributes":    attributes,
+			"relationships": JSONSchema{"type": "object"},
+			"links":         JSONSchema{"type": "object", "additionalProperties": JSONSchema{"type": "string"}},
+		},
+	}
+	document := func(data JSONSchema, properties JSONSchema) JSONSchema {
+		properties["data"] = data
+		return JSONSchema{"type": "object", "required": []string{"data"}, "properties": properties}
+	}
+	links := JSONSchema{"type": "object", "additionalProperties": JSONSchema{"type": "string"}}
+	included := JSONSchema{"type": "array", "items": JSONSchema{"type": "object"}}
+
+	item = document(object, JSONSchema{"included": included, "links": links})
+	items = document(JSONSchema{"type": "array", "items": object}, JSONSchema{
+		"included": included,
+		"links":    links,
+		"meta":     JSONSchema{"type": "object", "properties": JSONSchema{"total": JSONSchema{"type": "integer"}}},
+	})
+	input = document(JSONSchema{
+		"type":     "object",
+		"required": []string{"type"},
+		"properties": JSONSchema{
+			"type":       JSONSchema{"type": "string", "const": typ},
+			"attributes": attributes,
+		},
+	}, JSONSchema{})
+	return item, items, input
+}
+
+// jsonAPIContent moves the application/json content to the JSON:API media type.
+func jsonAPIContent(content map[string]OpenAPIMediaType) {
+	if media, ok := content[echo.MIMEApplicationJSON]; ok {
+		delete(content, echo.MIMEApplicationJSON)
+		content[MIMEApplicationJSONAPI] = media
+	}
+}
+
+// jsonPatchSchema is the JSON Schema of JSON Patch (RFC 6902) documents.
+var jsonPatchSchema = JSONSchema{
+	"type": "array",
+	"items": JSONSchema{
+		"type":     "object",
+		"required": []string{"op", "path"},
+		"properties": JSONSchema{
+			"op":    JSONSchema{"type": "string", "enum": []string{"add", "remove", "replace", "move", "copy", "test"}},
+			"path":  JSONSchema{"type": "string"},
+			"from":  JSONSchema{"type": "string"},
+			"value": JSONSchema{},
+		},
+	},
+}
+
+func openAPIRequestBody(schema JSONSchema) *OpenAPIRequestBody {
+	if schema == nil {
+		return nil
+	}
+	return &OpenAPIRequestBody{
+		Required: true,
+		Content:  map[string]OpenAPIMediaType{echo.MIMEApplicationJSON: {Schema: schema}},
+	}
+}
+
+func openAPIResponse(code int, schema JSONSchema) OpenAPIResponse {
+	response := OpenAPIResponse{Description: http.StatusText(code)}
+	if schema != nil {
+		response.Content = map[string]OpenAPIMediaType{echo.MIMEApplicationJSON: {Schema: schema}}
+	}
+	return response
+}
+
+// openAPIPath converts the path parameters of an Echo route to OpenAPI templates (e.g. `/tests/:id' to `/tests/{id}').
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// visibleSchema returns the JSON Schema of the given type without the hidden fields.
+// The schemas holding hidden fields are inlined and not stored in schemas.
+func visibleSchema(t reflect.Type, hidden fieldTree, schemas map[string]JSONSchema) JSONSchema {
+	all := maps.Clone(schemas)
+	inlined := map[string]bool{}
+	schema := hideSchemaFields(jsonSchema(t, all), hidden, all, inlined)
+	for name, s := range all {
+		if _, ok := schemas[name]; !ok && !inlined[name] {
+			schemas[name] = s
+		}
+	}
+	return schema
+}
+
+// hideSchemaFields returns a copy of the schema without the fields of the hidden tree, the paths of arrays apply to their items.
+func hideSchemaFields(schema JSONSchema, hidden fieldTree, schemas map[string]JSONSchema, inlined map[string]bool) JSONSchema {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		inlined[name] = true
+		schema = schemas[name]
+	}
+
+	switch schema["type"] {
+	case "array":
+		if items, ok := schema["items"].(JSONSchema); ok {
+			schema = maps.Clone(schema)
+			schema["items"] = hideSchemaFields(items, hidden, schemas, inlined)
+		}
+	case "object":
+		properties, ok := schema["properties"].(JSONSchema)
+		if !ok {
+			return schema
+		}
+		schema, properties = maps.Clone(schema), maps.Clone(properties)
+		required, _ := schema["required"].([]string)
+		for k, child := range hidden {
+			if child == nil {
+				delete(properties, k)
+				required = slices.DeleteFunc(slices.Clone(required), func(field string) bool { return field == k })
+			} else if property, ok := properties[k].(JSONSchema); ok {
+				properties[k] = hideSchemaFields(property, child, schemas, inlined)
+			}
+		}
+		schema["properties"] = properties
+		delete(schema, "required")
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+	return schema
+}
+
+var schemaNameReplacer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// jsonSchema returns the JSON Schema of the given type.
+// Named structs are stored in schemas and referenced.
+func jsonSchema(t reflect.Type, schemas map[string]JSONSchema) JSONSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case reflect.TypeFor[time.Time]():
+		return JSONSchema{"type": "string", "format": "date-time"}
+	case reflect.TypeFor[json.RawMessage]():
+		return JSONSchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return JSONSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return JSONSchema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return JSONSchema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return JSONSchema{"type": "number"}
+	case reflect.String:
+		return JSONSchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return JSONSchema{"type": "string", "contentEncoding": "base64"}
+		}
+		return JSONSchema{"type": "array", "items": jsonSchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return JSONSchema{"type": "object", "additionalProperties": jsonSchema(t.Elem(), schemas)}
+	case reflect.Struct:
+	default:
+		return JSONSchema{}
+	}
+
+	name := schemaNameReplacer.ReplaceAllString(t.Name(), "_")
+	ref := JSONSchema{"$ref": "#/components/schemas/" + name}
+	if name != "" {
+		if _, ok := schemas[name]; ok {
+			return ref
+		}
+		schemas[name] = JSONSchema{} // Recursive types
+	}
+
+	properties := JSONSchema{}
+	var required []string
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		tag, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = field.Name
+		}
+
+		schema := jsonSchema(field.Type, schemas)
+		if strings.Contains(options, "string") && schema["type"] != "string" {
+			schema = JSONSchema{"type": "string"}
+		}
+		properties[tag] = schema
+		if !strings.Contains(options, "omitempty") && !strings.Contains(options, "omitzero") {
+			required = append(required, tag)
+		}
+	}
+
+	schema := JSONSchema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	if name == "" {
+		return schema
+	}
+
+	schemas[name] = schema
+	return ref
+}
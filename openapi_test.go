@@ -0,0 +1,160 @@
+package middlewarex_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPI(t *testing.T) {
+	e := echo.New()
+	albums := middlewarex.CRUDOf(e.Group("/api"), "/albums", middlewarex.NewMemoryRepository[album, int]())
+	albums.CRUD("/tracks", &crudNestedCtrl{})
+	e.GET("/openapi.json", middlewarex.OpenAPIHandler(middlewarex.OpenAPI(middlewarex.OpenAPIConfig{Title: "Albums"}, albums)))
+
+	info := albums.Info()
+	assert.Equal(t, "/api/albums", info.Path)
+	assert.Equal(t, middlewarex.VerbCreate|middlewarex.VerbList|middlewarex.VerbShow|middlewarex.VerbUpdate|middlewarex.VerbReplace|middlewarex.VerbDelete, info.Verbs)
+	assert.Equal(t, "album", info.Type.Name())
+	assert.Equal(t, "int", info.IDType.Name())
+
+	rec := serve(e, http.MethodGet, "/openapi.json", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var document map[string]any
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &document))
+	assert.Equal(t, "3.1.0", document["openapi"])
+	assert.Equal(t, map[string]any{"title": "Albums", "version": "1.0.0"}, document["info"])
+
+	paths := document["paths"].(map[string]any)
+	assert.Len(t, paths, 4)
+	assert.Contains(t, paths, "/api/albums")
+	assert.Contains(t, paths, "/api/albums/{album_id}/tracks")
+	assert.Contains(t, paths, "/api/albums/{album_id}/tracks/{id}")
+
+	member := paths["/api/albums/{id}"].(map[string]any)
+	assert.ElementsMatch(t, []string{"get", "patch", "put", "delete"}, keys(member))
+	assert.JSONEq(t, `{
+		"operationId": "albums.show",
+		"tags": ["albums"],
+		"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+		"responses": {
+			"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/album"}}}},
+			"404": {"description": "Not Found"}
+		}
+	}`, marshal(t, member["get"]))
+
+	create := paths["/api/albums"].(map[string]any)["post"].(map[string]any)
+	assert.Contains(t, create["responses"], "201")
+	assert.NotNil(t, create["requestBody"])
+
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"id": {"type": "integer"},
+			"title": {"type": "string"},
+			"artist": {"type": "string"},
+			"year": {"type": "integer"},
+			"version": {"type": "integer"}
+		},
+		"required": ["id", "title", "artist", "year", "version"]
+	}`, marshal(t, document["components"].(map[string]any)["schemas"].(map[string]any)["album"]))
+}
+
+func TestOpenAPIJSONAPI(t *testing.T) {
+	e := echo.New()
+	books := middlewarex.CRUDOfWithConfig(e.Group(""), "/books", newBookRepository(), middlewarex.TypedCRUDConfig[book, int]{
+		JSONAPI: &middlewarex.JSONAPIConfig[book]{Type: "books"},
+	})
+	assert.Equal(t, "books", books.Info().JSONAPIType)
+
+	document := marshal(t, middlewarex.OpenAPI(middlewarex.OpenAPIConfig{}, books))
+	var v map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(document), &v))
+	paths := v["paths"].(map[string]any)
+
+	show := paths["/books/{id}"].(map[string]any)["get"].(map[string]any)
+	content := show["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)
+	assert.Equal(t, []string{middlewarex.MIMEApplicationJSONAPI}, keys(content))
+	assert.JSONEq(t, `{
+		"type": "object",
+		"required": ["data"],
+		"properties": {
+			"data": {
+				"type": "object",
+				"required": ["type", "id"],
+				"properties": {
+					"type": {"type": "string", "const": "books"},
+					"id": {"type": "string"},
+					"attributes": {"$ref": "#/components/schemas/book"},
+					"relationships": {"type": "object"},
+					"links": {"type": "object", "additionalProperties": {"type": "string"}}
+				}
+			},
+			"included": {"type": "array", "items": {"type": "object"}},
+			"links": {"type": "object", "additionalProperties": {"type": "string"}}
+		}
+	}`, marshal(t, content[middlewarex.MIMEApplicationJSONAPI].(map[string]any)["schema"]))
+
+	list := paths["/books"].(map[string]any)["get"].(map[string]any)
+	content = list["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)
+	schema := content[middlewarex.MIMEApplicationJSONAPI].(map[string]any)["schema"].(map[string]any)
+	assert.Equal(t, "array", schema["properties"].(map[string]any)["data"].(map[string]any)["type"])
+
+	update := paths["/books/{id}"].(map[string]any)["patch"].(map[string]any)
+	content = update["requestBody"].(map[string]any)["content"].(map[string]any)
+	assert.ElementsMatch(t, []string{middlewarex.MIMEApplicationJSONAPI, middlewarex.MIMEApplicationMergePatch, middlewarex.MIMEApplicationJSONPatch}, keys(content))
+	assert.JSONEq(t, `{
+		"type": "object",
+		"required": ["data"],
+		"properties": {
+			"data": {
+				"type": "object",
+				"required": ["type"],
+				"properties": {
+					"type": {"type": "string", "const": "books"},
+					"attributes": {"$ref": "#/components/schemas/book"}
+				}
+			}
+		}
+	}`, marshal(t, content[middlewarex.MIMEApplicationJSONAPI].(map[string]any)["schema"]))
+}
+
+func TestVersionedOpenAPI(t *testing.T) {
+	e := echo.New()
+	v1 := middlewarex.CRUD(e.Group("/v1"), "/tests", &crud1ctrl{})
+	v2 := middlewarex.CRUD(e.Group("/v2"), "/tests", &crud2ctrl{})
+
+	documents := middlewarex.VersionedOpenAPI(middlewarex.OpenAPIConfig{}, []string{"vnd.github.v1", "vnd.github.v2"}, v1, v2)
+	if assert.Len(t, documents, 2) {
+		assert.Equal(t, "vnd.github.v1", documents["vnd.github.v1"].Info.Version)
+		assert.Len(t, documents["vnd.github.v1"].Paths["/tests/{id}"], 3)
+		assert.Len(t, documents["vnd.github.v2"].Paths["/tests/{id}"], 2)
+		assert.NotContains(t, documents["vnd.github.v2"].Paths["/tests"], "post")
+
+		operation := documents["vnd.github.v2"].Paths["/tests"]["get"]
+		if assert.NotNil(t, operation) && assert.Len(t, operation.Parameters, 1) {
+			assert.Equal(t, middlewarex.XApplicationVersion, operation.Parameters[0].Name)
+			assert.Equal(t, "header", operation.Parameters[0].In)
+		}
+	}
+}
+
+func keys(m map[string]any) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func marshal(t *testing.T, v any) string {
+	t.Helper()
+	payload, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return string(payload)
+}
@@ -1,6 +1,10 @@
 package middlewarex
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -51,9 +55,21 @@ type (
 		// - "cookie:<name>"
 		TokenLookup string
 
+		// Extractor defines a custom function to extract the token from the request.
+		// When set, TokenLookup is ignored.
+		// Optional.
+		Extractor PASETOExtractor
+
 		// AuthScheme to be used in the Authorization header.
 		// Optional. Default value "Bearer".
 		AuthScheme string
+
+		// ClaimHeaders maps request header names to token claims.
+		// After a successful validation, the claims are forwarded to the upstream handlers as request headers.
+		// Nested claims are addressed with a dotted path (e.g. "org.id").
+		// Client-supplied copies of those headers are always removed from the request.
+		// Optional.
+		ClaimHeaders map[string]string
 	}
 
 	// Token represents a PASETO JSONToken with its footer.
@@ -71,13 +87,26 @@ type (
 	// PASETOErrorHandlerWithContext is almost identical to PASETOErrorHandler, but it's passed the current context.
 	PASETOErrorHandlerWithContext func(error, *echo.Context) error
 
-	pasetoExtractor func(*echo.Context) (string, error)
+	// PASETOVerifier extracts, decrypts and validates PASETO tokens from HTTP requests.
+	// It does not depend on Echo and is shared by the Echo and net/http middlewares.
+	PASETOVerifier struct {
+		config    PASETOConfig
+		extractor PASETOExtractor
+	}
+
+	// PASETOExtractor defines a function which extracts the raw token from the request.
+	// It must return ErrPASETOMissing when no token is found.
+	PASETOExtractor func(*http.Request) (string, error)
+
+	tokenContextKey struct{}
 )
 
 // Errors
 var (
 	ErrPASETOMissing     = echo.NewHTTPError(http.StatusBadRequest, "missing or malformed paseto")
 	ErrPASETOUnsupported = echo.NewHTTPError(http.StatusBadRequest, "unsupported paseto version/purpose")
+
+	errInvalidPASETO = echo.HTTPError{Code: http.StatusUnauthorized, Message: "invalid or expired paseto"}
 )
 
 // DefaultPASETOConfig is the default PASETO auth middleware config.
@@ -101,41 +130,28 @@ func PASETO(key []byte) echo.MiddlewareFunc {
 }
 
 // PASETOWithConfig returns a PASETO auth middleware with config.
+// It panics if the config is invalid, see NewPASETO.
 func PASETOWithConfig(config PASETOConfig) echo.MiddlewareFunc {
-	if len(config.SigningKey) != 32 {
-		panic("SigningKey must be 32 bytes length")
-	}
-	// Defaults
-	if config.Skipper == nil {
-		config.Skipper = DefaultPASETOConfig.Skipper
-	}
-	if config.ContextKey == "" {
-		config.ContextKey = DefaultPASETOConfig.ContextKey
-	}
-	if config.Validators == nil {
-		config.Validators = DefaultPASETOConfig.Validators
-	}
-	if config.TokenLookup == "" {
-		config.TokenLookup = DefaultPASETOConfig.TokenLookup
-	}
-	if config.AuthScheme == "" {
-		config.AuthScheme = DefaultPASETOConfig.AuthScheme
+	mw, err := NewPASETO(config)
+	if err != nil {
+		panic(err.Error())
 	}
+	return mw
+}
 
-	// Initialize
-	parts := strings.Split(config.TokenLookup, ":")
-	extractor := pasetoFromHeader(parts[1], config.AuthScheme)
-	switch parts[0] {
-	case "query":
-		extractor = pasetoFromQuery(parts[1])
-	case "param":
-		extractor = pasetoFromParam(parts[1])
-	case "cookie":
-		extractor = pasetoFromCookie(parts[1])
+// NewPASETO returns a PASETO auth middleware with config
+// or an error if the config is invalid.
+func NewPASETO(config PASETOConfig) (echo.MiddlewareFunc, error) {
+	verifier, err := NewPASETOVerifier(config)
+	if err != nil {
+		return nil, err
 	}
+	config = verifier.config
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c *echo.Context) error {
+			verifier.stripClaimHeaders(c.Request().Header)
+
 			if config.Skipper(c) {
 				return next(c)
 			}
@@ -144,63 +160,326 @@ func PASETOWithConfig(config PASETOConfig) echo.MiddlewareFunc {
 				config.BeforeFunc(c)
 			}
 
-			auth, err := extractor(c)
+			auth, err := extractPASETO(c, verifier.extractor)
+			var token Token
+			if err == nil {
+				token, err = verifier.decrypt(auth)
+			}
+			if err == nil {
+				// Store user information from token into context.
+				// It is stored before validation so ErrorHandlerWithContext can inspect an expired token.
+				c.Set(config.ContextKey, token)
+				err = verifier.validate(token)
+			}
 			if err != nil {
 				if config.ErrorHandler != nil {
 					return config.ErrorHandler(err)
 				}
-
 				if config.ErrorHandlerWithContext != nil {
 					return config.ErrorHandlerWithContext(err, c)
 				}
 				return err
 			}
 
-			// TODO: support v2.public
-			if !strings.HasPrefix(auth, "v2.local.") {
-				if config.ErrorHandler != nil {
-					return config.ErrorHandler(ErrPASETOUnsupported)
-				}
+			verifier.forwardClaims(c.Request().Header, token)
 
-				if config.ErrorHandlerWithContext != nil {
-					return config.ErrorHandlerWithContext(ErrPASETOUnsupported, c)
-				}
-				return ErrPASETOUnsupported
+			if config.SuccessHandler != nil {
+				config.SuccessHandler(c)
 			}
+			return next(c)
+		}
+	}, nil
+}
 
-			var token Token
-			err = paseto.Decrypt(auth, config.SigningKey, &token.JSONToken, &token.Footer)
-			if err == nil {
-				// Store user information from token into context.
-				c.Set(config.ContextKey, token)
+// PASETOHandler returns a net/http PASETO auth middleware.
+// It behaves like PASETO but stores the token in the request's context.Context.
+func PASETOHandler(key []byte) func(http.Handler) http.Handler {
+	c := DefaultPASETOConfig
+	c.SigningKey = key
+	return PASETOHandlerWithConfig(c)
+}
+
+// PASETOHandlerWithConfig returns a net/http PASETO auth middleware with config.
+// Echo specific fields (Skipper, BeforeFunc, SuccessHandler and ErrorHandlerWithContext) are ignored.
+// The token can be retrieved from the request's context with TokenFromContext.
+// It panics if the config is invalid, see NewPASETOHandler.
+func PASETOHandlerWithConfig(config PASETOConfig) func(http.Handler) http.Handler {
+	mw, err := NewPASETOHandler(config)
+	if err != nil {
+		panic(err.Error())
+	}
+	return mw
+}
 
-				err = token.Validate(append(config.Validators, paseto.ValidAt(time.Now()))...)
-				if err == nil {
-					if config.SuccessHandler != nil {
-						config.SuccessHandler(c)
-					}
-					return next(c)
+// NewPASETOHandler returns a net/http PASETO auth middleware with config
+// or an error if the config is invalid.
+func NewPASETOHandler(config PASETOConfig) (func(http.Handler) http.Handler, error) {
+	verifier, err := NewPASETOVerifier(config)
+	if err != nil {
+		return nil, err
+	}
+	config = verifier.config
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			verifier.stripClaimHeaders(r.Header)
+
+			token, err := verifier.Verify(r)
+			if err != nil {
+				if config.ErrorHandler != nil {
+					err = config.ErrorHandler(err)
 				}
+				if err != nil {
+					writeHTTPError(w, err)
+				}
+				return
 			}
 
-			if config.ErrorHandler != nil {
-				return config.ErrorHandler(err)
-			}
-			if config.ErrorHandlerWithContext != nil {
-				return config.ErrorHandlerWithContext(err, c)
-			}
-			return echo.HTTPError{
-				Code:    http.StatusUnauthorized,
-				Message: "invalid or expired paseto",
-			}.Wrap(err)
+			verifier.forwardClaims(r.Header, token)
+			next.ServeHTTP(w, r.WithContext(ContextWithToken(r.Context(), token)))
+		})
+	}, nil
+}
+
+// ContextWithToken returns a copy of ctx holding the given token.
+func ContextWithToken(ctx context.Context, token Token) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, token)
+}
+
+// TokenFromContext returns the token stored in ctx by PASETOHandler.
+func TokenFromContext(ctx context.Context) (Token, bool) {
+	token, ok := ctx.Value(tokenContextKey{}).(Token)
+	return token, ok
+}
+
+// NewPASETOVerifier returns a PASETOVerifier for the given config
+// or an error if the config is invalid.
+// Missing config fields are filled with DefaultPASETOConfig values.
+func NewPASETOVerifier(config PASETOConfig) (*PASETOVerifier, error) {
+	if err := checkPASETOKey("SigningKey", config.SigningKey); err != nil {
+		return nil, err
+	}
+	if config.ErrorHandler != nil && config.ErrorHandlerWithContext != nil {
+		return nil, errors.New("paseto: ErrorHandler and ErrorHandlerWithContext are mutually exclusive")
+	}
+	for header, claim := range config.ClaimHeaders {
+		if header == "" || claim == "" {
+			return nil, fmt.Errorf("paseto: ClaimHeaders has an empty header or claim (%q: %q)", header, claim)
 		}
 	}
+
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultPASETOConfig.Skipper
+	}
+	if config.ContextKey == "" {
+		config.ContextKey = DefaultPASETOConfig.ContextKey
+	}
+	if config.Validators == nil {
+		config.Validators = DefaultPASETOConfig.Validators
+	}
+	if config.TokenLookup == "" {
+		config.TokenLookup = DefaultPASETOConfig.TokenLookup
+	}
+	if config.AuthScheme == "" {
+		config.AuthScheme = DefaultPASETOConfig.AuthScheme
+	}
+
+	// Initialize
+	extractor := config.Extractor
+	if extractor == nil {
+		var err error
+		extractor, err = pasetoLookup(config.TokenLookup, config.AuthScheme)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &PASETOVerifier{
+		config:    config,
+		extractor: extractor,
+	}, nil
+}
+
+// Verify extracts the token from the request, decrypts and validates it.
+func (v *PASETOVerifier) Verify(r *http.Request) (Token, error) {
+	auth, err := v.extractor(r)
+	if err != nil {
+		return Token{}, err
+	}
+	return v.VerifyToken(auth)
+}
+
+// VerifyToken decrypts and validates the given raw token.
+func (v *PASETOVerifier) VerifyToken(auth string) (Token, error) {
+	token, err := v.decrypt(auth)
+	if err == nil {
+		err = v.validate(token)
+	}
+	if err != nil {
+		return Token{}, err
+	}
+	return token, nil
+}
+
+func (v *PASETOVerifier) decrypt(auth string) (Token, error) {
+	// TODO: support v2.public
+	if !strings.HasPrefix(auth, "v2.local.") {
+		return Token{}, ErrPASETOUnsupported
+	}
+
+	var token Token
+	if err := paseto.Decrypt(auth, v.config.SigningKey, &token.JSONToken, &token.Footer); err != nil {
+		return Token{}, errInvalidPASETO.Wrap(err)
+	}
+	return token, nil
 }
 
-// pasetoFromHeader returns a `pasetoExtractor` that extracts token from the request header.
-func pasetoFromHeader(header string, authScheme string) pasetoExtractor {
-	return func(c *echo.Context) (string, error) {
-		auth := c.Request().Header.Get(header)
+func (v *PASETOVerifier) validate(token Token) error {
+	if err := token.Validate(append(v.config.Validators, paseto.ValidAt(time.Now()))...); err != nil {
+		return errInvalidPASETO.Wrap(err)
+	}
+	return nil
+}
+
+// stripClaimHeaders removes the client-supplied copies of the forwarded claim headers.
+func (v *PASETOVerifier) stripClaimHeaders(h http.Header) {
+	for header := range v.config.ClaimHeaders {
+		h.Del(header)
+	}
+}
+
+// forwardClaims sets the configured claim headers from the given token.
+// Missing claims are not forwarded.
+func (v *PASETOVerifier) forwardClaims(h http.Header, token Token) {
+	for header, claim := range v.config.ClaimHeaders {
+		value, ok := token.Claim(claim)
+		if !ok {
+			continue
+		}
+		h.Set(header, claimString(value))
+	}
+}
+
+// Claim returns the value of the claim at the given dotted path (e.g. "sub" or "org.id").
+// Values are the ones decoded from the JSON payload (string, float64, bool, []any or map[string]any).
+func (t Token) Claim(path string) (any, bool) {
+	keys := strings.Split(path, ".")
+
+	var value any
+	if err := t.Get(keys[0], &value); err != nil {
+		return nil, false
+	}
+
+	for _, key := range keys[1:] {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// claimString formats a claim value as a header value.
+func claimString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case []any:
+		values := make([]string, len(v))
+		for i := range v {
+			values[i] = claimString(v[i])
+		}
+		return strings.Join(values, ",")
+	case map[string]any:
+		b, _ := json.Marshal(v)
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// checkPASETOKey checks that the named key can be used for v2.local tokens.
+func checkPASETOKey(name string, key []byte) error {
+	if len(key) != 32 {
+		return fmt.Errorf("paseto: %s must be 32 bytes length, got %d bytes", name, len(key))
+	}
+	return nil
+}
+
+// pasetoLookup returns the `PASETOExtractor` described by the given "<source>:<name>" lookup.
+func pasetoLookup(lookup string, authScheme string) (PASETOExtractor, error) {
+	source, name, ok := strings.Cut(lookup, ":")
+	if !ok || source == "" || name == "" {
+		return nil, fmt.Errorf("paseto: TokenLookup must be in the form of \"<source>:<name>\", got %q", lookup)
+	}
+
+	switch source {
+	case "header":
+		return PASETOFromHeader(name, authScheme), nil
+	case "query":
+		return PASETOFromQuery(name), nil
+	case "param":
+		return PASETOFromParam(name), nil
+	case "cookie":
+		return PASETOFromCookie(name), nil
+	default:
+		return nil, fmt.Errorf("paseto: TokenLookup has an unknown source %q, expecting one of header, query, param or cookie", source)
+	}
+}
+
+// extractPASETO extracts the token from the request of the given context with Echo's path values
+// exposed through http.Request.PathValue.
+func extractPASETO(c *echo.Context, extractor PASETOExtractor) (string, error) {
+	r := c.Request()
+	values := c.PathValues()
+	if len(values) == 0 {
+		return extractor(r)
+	}
+
+	shallow := r.WithContext(r.Context())
+	for _, v := range values {
+		shallow.SetPathValue(v.Name, v.Value)
+	}
+	auth, err := extractor(shallow)
+
+	// The body is shared, keep the form parsed by the extractor (e.g. PASETOFromForm) for the handlers
+	r.Form, r.PostForm, r.MultipartForm = shallow.Form, shallow.PostForm, shallow.MultipartForm
+	return auth, err
+}
+
+// writeHTTPError writes the given error as a plain text response.
+func writeHTTPError(w http.ResponseWriter, err error) {
+	code := echo.StatusCode(err)
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+
+	message := http.StatusText(code)
+	var he *echo.HTTPError
+	if errors.As(err, &he) && he.Message != "" {
+		message = he.Message
+	}
+	http.Error(w, message, code)
+}
+
+// PASETOFromHeader returns a `PASETOExtractor` that extracts token from the request header.
+// An empty authScheme means that the whole header value is the token.
+func PASETOFromHeader(header string, authScheme string) PASETOExtractor {
+	return func(r *http.Request) (string, error) {
+		auth := r.Header.Get(header)
+		if authScheme == "" {
+			if auth == "" {
+				return "", ErrPASETOMissing
+			}
+			return auth, nil
+		}
+
 		l := len(authScheme)
 		if len(auth) > l+1 && auth[:l] == authScheme {
 			return auth[l+1:], nil
@@ -209,10 +488,10 @@ func pasetoFromHeader(header string, authScheme string) pasetoExtractor {
 	}
 }
 
-// pasetoFromQuery returns a `pasetoExtractor` that extracts token from the query string.
-func pasetoFromQuery(param string) pasetoExtractor {
-	return func(c *echo.Context) (string, error) {
-		token := c.QueryParam(param)
+// PASETOFromQuery returns a `PASETOExtractor` that extracts token from the query string.
+func PASETOFromQuery(param string) PASETOExtractor {
+	return func(r *http.Request) (string, error) {
+		token := r.URL.Query().Get(param)
 		if token == "" {
 			return "", ErrPASETOMissing
 		}
@@ -220,10 +499,10 @@ func pasetoFromQuery(param string) pasetoExtractor {
 	}
 }
 
-// pasetoFromParam returns a `pasetoExtractor` that extracts token from the url param string.
-func pasetoFromParam(param string) pasetoExtractor {
-	return func(c *echo.Context) (string, error) {
-		token := c.Param(param)
+// PASETOFromParam returns a `PASETOExtractor` that extracts token from the url param string.
+func PASETOFromParam(param string) PASETOExtractor {
+	return func(r *http.Request) (string, error) {
+		token := r.PathValue(param)
 		if token == "" {
 			return "", ErrPASETOMissing
 		}
@@ -231,13 +510,40 @@ func pasetoFromParam(param string) pasetoExtractor {
 	}
 }
 
-// pasetoFromCookie returns a `pasetoExtractor` that extracts token from the named cookie.
-func pasetoFromCookie(name string) pasetoExtractor {
-	return func(c *echo.Context) (string, error) {
-		cookie, err := c.Cookie(name)
+// PASETOFromCookie returns a `PASETOExtractor` that extracts token from the named cookie.
+func PASETOFromCookie(name string) PASETOExtractor {
+	return func(r *http.Request) (string, error) {
+		cookie, err := r.Cookie(name)
 		if err != nil {
 			return "", ErrPASETOMissing
 		}
 		return cookie.Value, nil
 	}
 }
+
+// PASETOFromForm returns a `PASETOExtractor` that extracts token from the named form field.
+func PASETOFromForm(name string) PASETOExtractor {
+	return func(r *http.Request) (string, error) {
+		token := r.FormValue(name)
+		if token == "" {
+			return "", ErrPASETOMissing
+		}
+		return token, nil
+	}
+}
+
+// PASETOFromAny returns a `PASETOExtractor` that tries the given extractors in order
+// and returns the first found token.
+func PASETOFromAny(extractors ...PASETOExtractor) PASETOExtractor {
+	return func(r *http.Request) (string, error) {
+		var err error = ErrPASETOMissing
+		for _, extractor := range extractors {
+			var token string
+			token, err = extractor(r)
+			if err == nil {
+				return token, nil
+			}
+		}
+		return "", err
+	}
+}
@@ -3,6 +3,7 @@ package middlewarex_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -44,11 +45,6 @@ func TestPASETORace(t *testing.T) {
 }
 
 func TestPASETO(t *testing.T) {
-	e := echo.New()
-	handler := func(c *echo.Context) error {
-		return c.String(http.StatusOK, "test")
-	}
-
 	token := "v2.local.Q0O8UKihblHPFEjLH0r1dJKntyLDpPItRvbpC49xR_lbdc8Hfx7K4kA6TfFffTD5BAaMXiqnp1yShA"
 	validkey := []byte("400c48a557be10254d235cf8c506e6fe")
 	invalidkey := []byte("invalid-57be10254d235cf8c506e6fe")
@@ -64,13 +60,14 @@ func TestPASETO(t *testing.T) {
 	}
 
 	tests := []struct {
-		expPanic   bool
-		expErrCode int // 0 for Success
-		config     middlewarex.PASETOConfig
-		reqURL     string // "/" if empty
-		hdrAuth    string
-		hdrCookie  string // test.Request doesn't provide SetCookie(); use name=val
-		info       string
+		expPanic    bool
+		expErrCode  int // 0 for Success
+		config      middlewarex.PASETOConfig
+		reqURL      string // "/" if empty
+		hdrAuth     string
+		hdrCookie   string // test.Request doesn't provide SetCookie(); use name=val
+		hdrAPIToken string
+		info        string
 	}{
 		{
 			expPanic: true,
@@ -218,6 +215,64 @@ func TestPASETO(t *testing.T) {
 			info:       "Empty cookie",
 		},
 		//
+		// Custom extractor
+		//
+		{
+			config: middlewarex.PASETOConfig{
+				SigningKey: validkey,
+				Extractor:  middlewarex.PASETOFromHeader("X-Api-Token", ""),
+			},
+			hdrAPIToken: token,
+			info:        "Valid custom header without scheme",
+		},
+		{
+			config: middlewarex.PASETOConfig{
+				SigningKey: validkey,
+				Extractor:  middlewarex.PASETOFromHeader("X-Api-Token", ""),
+			},
+			expErrCode: http.StatusBadRequest,
+			info:       "Empty custom header without scheme",
+		},
+		{
+			config: middlewarex.PASETOConfig{
+				SigningKey:  validkey,
+				TokenLookup: middlewarex.DefaultPASETOConfig.TokenLookup,
+				Extractor:   middlewarex.PASETOFromHeader("X-Api-Token", ""),
+			},
+			hdrAPIToken: token,
+			info:        "Custom extractor with TokenLookup",
+		},
+		{
+			config: middlewarex.PASETOConfig{
+				SigningKey: validkey,
+				Extractor: middlewarex.PASETOFromAny(
+					middlewarex.PASETOFromHeader("X-Api-Token", ""),
+					middlewarex.PASETOFromQuery("paseto"),
+				),
+			},
+			reqURL: "/?paseto=" + token,
+			info:   "Valid combined extractors",
+		},
+		{
+			config: middlewarex.PASETOConfig{
+				SigningKey: validkey,
+				Extractor:  middlewarex.PASETOFromForm("paseto"),
+			},
+			reqURL: "/?paseto=" + token,
+			info:   "Valid form extractor",
+		},
+		{
+			config: middlewarex.PASETOConfig{
+				SigningKey: validkey,
+				Extractor: middlewarex.PASETOFromAny(
+					middlewarex.PASETOFromHeader("X-Api-Token", ""),
+					middlewarex.PASETOFromQuery("paseto"),
+				),
+			},
+			expErrCode: http.StatusBadRequest,
+			info:       "Missing token with combined extractors",
+		},
+		//
 		// Timestamps validation
 		//
 		{
@@ -284,34 +339,268 @@ func TestPASETO(t *testing.T) {
 			test.reqURL = "/"
 		}
 
-		req := httptest.NewRequest(http.MethodGet, test.reqURL, nil)
-		res := httptest.NewRecorder()
-		req.Header.Set(echo.HeaderAuthorization, test.hdrAuth)
-		req.Header.Set(echo.HeaderCookie, test.hdrCookie)
-		c := e.NewContext(req, res)
-
-		if test.reqURL == "/"+token {
-			c.SetPathValues([]echo.PathValue{{Name: "paseto", Value: token}})
-		}
-
 		if test.expPanic {
 			assert.Panics(t, func() {
 				middlewarex.PASETOWithConfig(test.config)
 			}, test.info)
+			assert.Panics(t, func() {
+				middlewarex.PASETOHandlerWithConfig(test.config)
+			}, test.info)
 			continue
 		}
 
-		if test.expErrCode != 0 {
-			h := middlewarex.PASETOWithConfig(test.config)(handler)
-			he := h(c).(*echo.HTTPError)
-			assert.Equal(t, test.expErrCode, he.Code, test.info)
-			continue
+		newRequest := func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, test.reqURL, nil)
+			req.Header.Set(echo.HeaderAuthorization, test.hdrAuth)
+			req.Header.Set(echo.HeaderCookie, test.hdrCookie)
+			req.Header.Set("X-Api-Token", test.hdrAPIToken)
+			return req
 		}
 
-		h := middlewarex.PASETOWithConfig(test.config)(handler)
-		if assert.NoError(t, h(c), test.info) {
-			tk := c.Get(middlewarex.DefaultPASETOConfig.ContextKey).(middlewarex.Token)
-			assert.Equal(t, "John Doe", tk.Subject, test.info)
+		for name, run := range pasetoRunners {
+			code, tk := run(test.config, newRequest(), token)
+
+			if test.expErrCode != 0 {
+				assert.Equal(t, test.expErrCode, code, name+": "+test.info)
+				continue
+			}
+
+			if assert.Equal(t, http.StatusOK, code, name+": "+test.info) {
+				assert.Equal(t, "John Doe", tk.Subject, name+": "+test.info)
+			}
 		}
 	}
 }
+
+func TestNewPASETO(t *testing.T) {
+	key := []byte("400c48a557be10254d235cf8c506e6fe")
+	errorHandler := func(err error) error { return err }
+	errorHandlerWithContext := func(err error, _ *echo.Context) error { return err }
+
+	tests := []struct {
+		config middlewarex.PASETOConfig
+		expErr string // empty for success
+		info   string
+	}{
+		{
+			config: middlewarex.PASETOConfig{SigningKey: key},
+			info:   "Valid config",
+		},
+		{
+			config: middlewarex.PASETOConfig{SigningKey: key, TokenLookup: "cookie:paseto"},
+			info:   "Valid TokenLookup",
+		},
+		{
+			config: middlewarex.PASETOConfig{SigningKey: []byte("too small")},
+			expErr: "paseto: SigningKey must be 32 bytes length, got 9 bytes",
+			info:   "Too small signing key",
+		},
+		{
+			config: middlewarex.PASETOConfig{SigningKey: key, TokenLookup: "header"},
+			expErr: `paseto: TokenLookup must be in the form of "<source>:<name>", got "header"`,
+			info:   "TokenLookup without name",
+		},
+		{
+			config: middlewarex.PASETOConfig{SigningKey: key, TokenLookup: "query:"},
+			expErr: `paseto: TokenLookup must be in the form of "<source>:<name>", got "query:"`,
+			info:   "TokenLookup with empty name",
+		},
+		{
+			config: middlewarex.PASETOConfig{SigningKey: key, TokenLookup: "body:paseto"},
+			expErr: `paseto: TokenLookup has an unknown source "body", expecting one of header, query, param or cookie`,
+			info:   "TokenLookup with unknown source",
+		},
+		{
+			config: middlewarex.PASETOConfig{
+				SigningKey:              key,
+				ErrorHandler:            errorHandler,
+				ErrorHandlerWithContext: errorHandlerWithContext,
+			},
+			expErr: "paseto: ErrorHandler and ErrorHandlerWithContext are mutually exclusive",
+			info:   "Conflicting error handlers",
+		},
+		{
+			config: middlewarex.PASETOConfig{
+				SigningKey:  key,
+				TokenLookup: "query:paseto",
+				Extractor:   middlewarex.PASETOFromQuery("paseto"),
+			},
+			info: "Extractor takes precedence over TokenLookup",
+		},
+	}
+
+	for _, test := range tests {
+		mw, err := middlewarex.NewPASETO(test.config)
+		h, herr := middlewarex.NewPASETOHandler(test.config)
+
+		if test.expErr == "" {
+			assert.NoError(t, err, test.info)
+			assert.NotNil(t, mw, test.info)
+			assert.NoError(t, herr, test.info)
+			assert.NotNil(t, h, test.info)
+			continue
+		}
+
+		assert.EqualError(t, err, test.expErr, test.info)
+		assert.Nil(t, mw, test.info)
+		assert.EqualError(t, herr, test.expErr, test.info)
+		assert.Nil(t, h, test.info)
+		assert.PanicsWithValue(t, test.expErr, func() {
+			middlewarex.PASETOWithConfig(test.config)
+		}, test.info)
+	}
+}
+
+func TestPASETOErrorHandlerWithContextExpiredToken(t *testing.T) {
+	key := []byte("400c48a557be10254d235cf8c506e6fe")
+	token, err := paseto.Encrypt(key, &paseto.JSONToken{Subject: "John Doe", Expiration: time.Now().Add(-time.Hour)}, "")
+	assert.NoError(t, err)
+
+	var subject string
+	mw := middlewarex.PASETOWithConfig(middlewarex.PASETOConfig{
+		SigningKey: key,
+		ErrorHandlerWithContext: func(err error, c *echo.Context) error {
+			if tk, ok := c.Get(middlewarex.DefaultPASETOConfig.ContextKey).(middlewarex.Token); ok {
+				subject = tk.Subject
+			}
+			return err
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+	err = mw(func(c *echo.Context) error { return c.NoContent(http.StatusOK) })(c)
+
+	assert.Equal(t, http.StatusUnauthorized, echo.StatusCode(err))
+	assert.Equal(t, "John Doe", subject)
+}
+
+func TestPASETOFromFormWithPathParams(t *testing.T) {
+	key := []byte("400c48a557be10254d235cf8c506e6fe")
+	token, err := paseto.Encrypt(key, &paseto.JSONToken{Subject: "John Doe"}, "")
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.POST("/docs/:id", func(c *echo.Context) error {
+		return c.String(http.StatusOK, c.Param("id")+" "+c.FormValue("title"))
+	}, middlewarex.PASETOWithConfig(middlewarex.PASETOConfig{
+		SigningKey: key,
+		Extractor:  middlewarex.PASETOFromForm("paseto"),
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/docs/42", strings.NewReader("paseto="+token+"&title=Dune"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "42 Dune", rec.Body.String())
+}
+
+func TestPASETOClaimHeaders(t *testing.T) {
+	key := []byte("400c48a557be10254d235cf8c506e6fe")
+
+	jt := paseto.JSONToken{Subject: "John Doe"}
+	jt.Set("tenant", "acme")
+	jt.Set("org", map[string]any{"id": 42, "roles": []string{"admin", "ops"}})
+	token, err := paseto.Encrypt(key, jt, "")
+	assert.NoError(t, err)
+
+	config := middlewarex.PASETOConfig{
+		SigningKey: key,
+		ClaimHeaders: map[string]string{
+			"X-User-Id":   "sub",
+			"X-Tenant":    "tenant",
+			"X-Org-Id":    "org.id",
+			"X-Org-Roles": "org.roles",
+			"X-Missing":   "org.missing",
+		},
+	}
+
+	newRequest := func(auth string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, auth)
+		req.Header.Set("X-User-Id", "spoofed")
+		req.Header.Set("X-Missing", "spoofed")
+		return req
+	}
+
+	runners := map[string]func(req *http.Request) (int, http.Header){
+		"echo": func(req *http.Request) (int, http.Header) {
+			var headers http.Header
+			h := middlewarex.PASETOWithConfig(config)(func(c *echo.Context) error {
+				headers = c.Request().Header
+				return c.NoContent(http.StatusOK)
+			})
+
+			if err := h(echo.New().NewContext(req, httptest.NewRecorder())); err != nil {
+				return err.(*echo.HTTPError).Code, req.Header
+			}
+			return http.StatusOK, headers
+		},
+		"net/http": func(req *http.Request) (int, http.Header) {
+			var headers http.Header
+			h := middlewarex.PASETOHandlerWithConfig(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				headers = r.Header
+			}))
+
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			if headers == nil {
+				return res.Code, req.Header
+			}
+			return res.Code, headers
+		},
+	}
+
+	for name, run := range runners {
+		code, headers := run(newRequest("Bearer " + token))
+		assert.Equal(t, http.StatusOK, code, name)
+		assert.Equal(t, "John Doe", headers.Get("X-User-Id"), name)
+		assert.Equal(t, "acme", headers.Get("X-Tenant"), name)
+		assert.Equal(t, "42", headers.Get("X-Org-Id"), name)
+		assert.Equal(t, "admin,ops", headers.Get("X-Org-Roles"), name)
+		assert.NotContains(t, headers, "X-Missing", name)
+
+		code, headers = run(newRequest("Bearer v2.local.invalid-token"))
+		assert.Equal(t, http.StatusUnauthorized, code, name)
+		assert.NotContains(t, headers, "X-User-Id", name)
+	}
+}
+
+// pasetoRunners runs the same request against the Echo and net/http flavors of the PASETO middleware.
+// They return the response status code and the token seen by the next handler.
+var pasetoRunners = map[string]func(config middlewarex.PASETOConfig, req *http.Request, param string) (int, middlewarex.Token){
+	"echo": func(config middlewarex.PASETOConfig, req *http.Request, param string) (int, middlewarex.Token) {
+		var tk middlewarex.Token
+		handler := func(c *echo.Context) error {
+			tk = c.Get(middlewarex.DefaultPASETOConfig.ContextKey).(middlewarex.Token)
+			return c.String(http.StatusOK, "test")
+		}
+
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+		if req.URL.Path == "/"+param {
+			c.SetPathValues([]echo.PathValue{{Name: "paseto", Value: param}})
+		}
+
+		if err := middlewarex.PASETOWithConfig(config)(handler)(c); err != nil {
+			return err.(*echo.HTTPError).Code, tk
+		}
+		return http.StatusOK, tk
+	},
+	"net/http": func(config middlewarex.PASETOConfig, req *http.Request, param string) (int, middlewarex.Token) {
+		var tk middlewarex.Token
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tk, _ = middlewarex.TokenFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		})
+
+		if req.URL.Path == "/"+param {
+			req.SetPathValue("paseto", param)
+		}
+
+		res := httptest.NewRecorder()
+		middlewarex.PASETOHandlerWithConfig(config)(handler).ServeHTTP(res, req)
+		return res.Code, tk
+	},
+}
@@ -0,0 +1,694 @@
+package middlewarex
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/echo/v5"
+	"github.com/labstack/echo/v5/middleware"
+)
+
+type (
+	// PolicyConfig defines the config for RequirePolicy middleware.
+	PolicyConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper middleware.Skipper
+
+		// Expr is the policy expression evaluated against the token claims.
+		// Required.
+		Expr string
+
+		// Context key where the PASETO middleware stored the token.
+		// Optional. Default value "paseto".
+		ContextKey string
+	}
+
+	// ClaimPolicy is a compiled policy expression evaluated against the claims of a Token.
+	//
+	// The expression language supports:
+	//   - claims addressed by their (dotted) name: role, org.id
+	//   - literals: "string", 'string', 42, 4.2, true, false, null, ["a", "b"]
+	//   - request values: param("name"), query("name"), header("name")
+	//   - comparisons: ==, !=, <, <=, >, >=, in
+	//   - boolean operators: &&, ||, ! and parentheses
+	//
+	// A bare operand is true when it is true or a non-empty value.
+	// For `in`, a list on the left side matches if any of its values is in the right side.
+	ClaimPolicy struct {
+		expr string
+		root policyNode
+	}
+
+	// PolicyRequest holds the request values available to a ClaimPolicy.
+	PolicyRequest struct {
+		Token  Token
+		Param  func(name string) string
+		Query  func(name string) string
+		Header func(name string) string
+	}
+
+	policyNode interface {
+		eval(r *PolicyRequest) any
+		source() string
+	}
+)
+
+// DefaultPolicyConfig is the default RequirePolicy middleware config.
+var DefaultPolicyConfig = PolicyConfig{
+	Skipper:    middleware.DefaultSkipper,
+	ContextKey: DefaultPASETOConfig.ContextKey,
+}
+
+// ErrPolicyUnauthenticated is returned by RequirePolicy when no token is found in the context.
+var ErrPolicyUnauthenticated = echo.NewHTTPError(http.StatusUnauthorized, "missing paseto")
+
+// RequirePolicy returns a middleware that authorizes requests with the given policy expression.
+// It must be used after the PASETO middleware.
+//
+// e.g. `role in ["admin","ops"] && tenant == param("tenant")`
+//
+// For denied requests, it returns "403 - Forbidden" error with the failed clause.
+func RequirePolicy(expr string) echo.MiddlewareFunc {
+	c := DefaultPolicyConfig
+	c.Expr = expr
+	return RequirePolicyWithConfig(c)
+}
+
+// RequirePolicyWithConfig returns a RequirePolicy middleware with config.
+// It panics if the expression is invalid, see NewRequirePolicy.
+func RequirePolicyWithConfig(config PolicyConfig) echo.MiddlewareFunc {
+	mw, err := NewRequirePolicy(config)
+	if err != nil {
+		panic(err.Error())
+	}
+	return mw
+}
+
+// NewRequirePolicy returns a RequirePolicy middleware with config
+// or an error if the expression is invalid.
+func NewRequirePolicy(config PolicyConfig) (echo.MiddlewareFunc, error) {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultPolicyConfig.Skipper
+	}
+	if config.ContextKey == "" {
+		config.ContextKey = DefaultPolicyConfig.ContextKey
+	}
+
+	policy, err := CompileClaimPolicy(config.Expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			token, ok := contextToken(c, config.ContextKey)
+			if !ok {
+				return ErrPolicyUnauthenticated
+			}
+
+			if clause, ok := policy.Eval(&PolicyRequest{
+				Token:  token,
+				Param:  c.Param,
+				Query:  c.QueryParam,
+				Header: c.Request().Header.Get,
+			}); !ok {
+				return echo.NewHTTPError(http.StatusForbidden, "policy denied: "+clause)
+			}
+
+			return next(c)
+		}
+	}, nil
+}
+
+// contextToken returns the token stored by the Echo or the net/http PASETO middleware.
+func contextToken(c *echo.Context, key string) (Token, bool) {
+	if token, ok := c.Get(key).(Token); ok {
+		return token, true
+	}
+	return TokenFromContext(c.Request().Context())
+}
+
+// CompileClaimPolicy parses the given policy expression.
+func CompileClaimPolicy(expr string) (*ClaimPolicy, error) {
+	p := &policyParser{expr: expr}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != policyEOF {
+		return nil, p.unexpected()
+	}
+
+	return &ClaimPolicy{expr: expr, root: root}, nil
+}
+
+// String returns the policy expression.
+func (p *ClaimPolicy) String() string {
+	return p.expr
+}
+
+// Eval evaluates the policy.
+// When the policy denies the request, it returns false and the failed clause.
+func (p *ClaimPolicy) Eval(r *PolicyRequest) (string, bool) {
+	if r.Param == nil {
+		r.Param = func(string) string { return "" }
+	}
+	if r.Query == nil {
+		r.Query = func(string) string { return "" }
+	}
+	if r.Header == nil {
+		r.Header = func(string) string { return "" }
+	}
+
+	return policyFailure(p.root, r)
+}
+
+// policyFailure evaluates the node and returns the deepest failed clause of `&&` chains.
+func policyFailure(n policyNode, r *PolicyRequest) (string, bool) {
+	if and, ok := n.(*policyBinary); ok && and.op == "&&" {
+		if clause, ok := policyFailure(and.left, r); !ok {
+			return clause, false
+		}
+		return policyFailure(and.right, r)
+	}
+
+	if policyTruthy(n.eval(r)) {
+		return "", true
+	}
+	return n.source(), false
+}
+
+// ------------------ //
+// AST                //
+// ------------------ //
+
+type (
+	policySource struct {
+		src string
+	}
+
+	policyLiteral struct {
+		policySource
+		value any
+	}
+
+	policyClaim struct {
+		policySource
+		path string
+	}
+
+	policyCall struct {
+		policySource
+		fn  string
+		arg string
+	}
+
+	policyList struct {
+		policySource
+		items []policyNode
+	}
+
+	policyNot struct {
+		policySource
+		operand policyNode
+	}
+
+	policyBinary struct {
+		policySource
+		op          string
+		left, right policyNode
+	}
+)
+
+func (n policySource) source() string {
+	return n.src
+}
+
+func (n *policyLiteral) eval(*PolicyRequest) any {
+	return n.value
+}
+
+func (n *policyClaim) eval(r *PolicyRequest) any {
+	v, _ := r.Token.Claim(n.path)
+	return v
+}
+
+func (n *policyCall) eval(r *PolicyRequest) any {
+	switch n.fn {
+	case "param":
+		return r.Param(n.arg)
+	case "query":
+		return r.Query(n.arg)
+	default: // header
+		return r.Header(n.arg)
+	}
+}
+
+func (n *policyList) eval(r *PolicyRequest) any {
+	values := make([]any, len(n.items))
+	for i, item := range n.items {
+		values[i] = item.eval(r)
+	}
+	return values
+}
+
+func (n *policyNot) eval(r *PolicyRequest) any {
+	return !policyTruthy(n.operand.eval(r))
+}
+
+func (n *policyBinary) eval(r *PolicyRequest) any {
+	switch n.op {
+	case "&&":
+		return policyTruthy(n.left.eval(r)) && policyTruthy(n.right.eval(r))
+	case "||":
+		return policyTruthy(n.left.eval(r)) || policyTruthy(n.right.eval(r))
+	}
+
+	left, right := n.left.eval(r), n.right.eval(r)
+	switch n.op {
+	case "==":
+		return policyEqual(left, right)
+	case "!=":
+		return !policyEqual(left, right)
+	case "in":
+		return policyIn(left, right)
+	default:
+		c, ok := policyCompare(left, right)
+		if !ok {
+			return false
+		}
+		switch n.op {
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	}
+}
+
+func policyTruthy(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case nil:
+		return false
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
+
+func policyEqual(a, b any) bool {
+	if c, ok := policyCompare(a, b); ok {
+		return c == 0
+	}
+	if a == nil || b == nil {
+		return a == b
+	}
+	return claimString(a) == claimString(b)
+}
+
+func policyIn(needle, haystack any) bool {
+	values, ok := haystack.([]any)
+	if !ok {
+		return false
+	}
+
+	if needles, ok := needle.([]any); ok {
+		for _, n := range needles {
+			if policyIn(n, values) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, v := range values {
+		if policyEqual(needle, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyCompare compares numbers, strings and numeric strings against numbers.
+// Two strings are always compared as strings, NaN values are not comparable.
+func policyCompare(a, b any) (int, bool) {
+	sa, aok := a.(string)
+	sb, bok := b.(string)
+	if aok && bok {
+		return strings.Compare(sa, sb), true
+	}
+
+	fa, aok := policyFloat(a)
+	fb, bok := policyFloat(b)
+	if aok && bok {
+		switch {
+		case math.IsNaN(fa) || math.IsNaN(fb):
+			return 0, false
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// policyDecimal matches the finite decimal literals, without exponent.
+var policyDecimal = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+func policyFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		if !policyDecimal.MatchString(v) {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil && !math.IsInf(f, 0)
+	default:
+		return 0, false
+	}
+}
+
+// ------------------ //
+// Parser             //
+// ------------------ //
+
+const (
+	policyEOF = iota
+	policyIdent
+	policyString
+	policyNumber
+	policyOperator
+)
+
+type (
+	policyToken struct {
+		kind  int
+		text  string
+		value any
+		start int
+	}
+
+	policyParser struct {
+		expr string
+		pos  int
+		prev int // End offset of the previous token
+		tok  policyToken
+	}
+)
+
+func (p *policyParser) parseOr() (policyNode, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *policyParser) parseAnd() (policyNode, error) {
+	return p.parseBinary(p.parseNot, "&&")
+}
+
+func (p *policyParser) parseBinary(operand func() (policyNode, error), op string) (policyNode, error) {
+	start := p.tok.start
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == policyOperator && p.tok.text == op {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &policyBinary{policySource: p.source(start), op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *policyParser) parseNot() (policyNode, error) {
+	if p.tok.kind != policyOperator || p.tok.text != "!" {
+		return p.parseComparison()
+	}
+
+	start := p.tok.start
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return &policyNot{policySource: p.source(start), operand: operand}, nil
+}
+
+func (p *policyParser) parseComparison() (policyNode, error) {
+	start := p.tok.start
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.tok.text
+	switch {
+	case p.tok.kind == policyIdent && op == "in":
+	case p.tok.kind == policyOperator && strings.Contains(" == != < <= > >= ", " "+op+" "):
+	default:
+		return left, nil
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &policyBinary{policySource: p.source(start), op: op, left: left, right: right}, nil
+}
+
+func (p *policyParser) parseOperand() (policyNode, error) {
+	tok := p.tok
+
+	switch tok.kind {
+	case policyString, policyNumber:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		return &policyLiteral{policySource: p.source(tok.start), value: tok.value}, nil
+	case policyIdent:
+		return p.parseIdent()
+	case policyOperator:
+		switch tok.text {
+		case "(":
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			return p.parseList()
+		}
+	}
+	return nil, p.unexpected()
+}
+
+func (p *policyParser) parseIdent() (policyNode, error) {
+	tok := p.tok
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	switch tok.text {
+	case "true", "false":
+		return &policyLiteral{policySource: p.source(tok.start), value: tok.text == "true"}, nil
+	case "null":
+		return &policyLiteral{policySource: p.source(tok.start)}, nil
+	case "in":
+		return nil, p.unexpectedToken(tok)
+	}
+
+	if p.tok.kind != policyOperator || p.tok.text != "(" {
+		return &policyClaim{policySource: p.source(tok.start), path: tok.text}, nil
+	}
+
+	switch tok.text {
+	case "param", "query", "header":
+	default:
+		return nil, fmt.Errorf("policy: unknown function %q at offset %d", tok.text, tok.start)
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	arg := p.tok
+	if arg.kind != policyString {
+		return nil, fmt.Errorf("policy: %s() expects a string argument at offset %d", tok.text, arg.start)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return &policyCall{policySource: p.source(tok.start), fn: tok.text, arg: arg.value.(string)}, nil
+}
+
+func (p *policyParser) parseList() (policyNode, error) {
+	start := p.tok.start
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	list := &policyList{}
+	for p.tok.kind != policyOperator || p.tok.text != "]" {
+		if len(list.items) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		item, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		list.items = append(list.items, item)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	list.policySource = p.source(start)
+	return list, nil
+}
+
+func (p *policyParser) expect(op string) error {
+	if p.tok.kind != policyOperator || p.tok.text != op {
+		return fmt.Errorf("policy: expecting %q but got %s", op, p.describe(p.tok))
+	}
+	return p.next()
+}
+
+func (p *policyParser) source(start int) policySource {
+	return policySource{src: p.expr[start:p.prev]}
+}
+
+func (p *policyParser) unexpected() error {
+	return p.unexpectedToken(p.tok)
+}
+
+func (p *policyParser) unexpectedToken(tok policyToken) error {
+	return fmt.Errorf("policy: unexpected %s", p.describe(tok))
+}
+
+func (p *policyParser) describe(tok policyToken) string {
+	if tok.kind == policyEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q at offset %d", tok.text, tok.start)
+}
+
+// next reads the next token.
+func (p *policyParser) next() error {
+	p.prev = p.tok.start + len(p.tok.text)
+
+	for p.pos < len(p.expr) && unicode.IsSpace(rune(p.expr[p.pos])) {
+		p.pos++
+	}
+
+	start := p.pos
+	if start >= len(p.expr) {
+		p.tok = policyToken{kind: policyEOF, start: start}
+		return nil
+	}
+
+	c := p.expr[start]
+	switch {
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.expr) && policyIdentChar(p.expr[p.pos]) {
+			p.pos++
+		}
+		p.tok = policyToken{kind: policyIdent, text: p.expr[start:p.pos], start: start}
+	case c == '-' || (c >= '0' && c <= '9'):
+		p.pos++
+		for p.pos < len(p.expr) && (p.expr[p.pos] == '.' || (p.expr[p.pos] >= '0' && p.expr[p.pos] <= '9')) {
+			p.pos++
+		}
+		text := p.expr[start:p.pos]
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("policy: invalid number %q at offset %d", text, start)
+		}
+		p.tok = policyToken{kind: policyNumber, text: text, value: f, start: start}
+	case c == '"' || c == '\'':
+		p.pos++
+		for p.pos < len(p.expr) && p.expr[p.pos] != c {
+			if p.expr[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.expr) {
+			return fmt.Errorf("policy: unterminated string at offset %d", start)
+		}
+		p.pos++
+		text := p.expr[start:p.pos]
+
+		raw := text
+		if c == '\'' {
+			raw = `"` + strings.ReplaceAll(strings.ReplaceAll(text[1:len(text)-1], `\'`, `'`), `"`, `\"`) + `"`
+		}
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return fmt.Errorf("policy: invalid string %s at offset %d", text, start)
+		}
+		p.tok = policyToken{kind: policyString, text: text, value: value, start: start}
+	default:
+		for _, op := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","} {
+			if strings.HasPrefix(p.expr[start:], op) {
+				p.pos += len(op)
+				p.tok = policyToken{kind: policyOperator, text: op, start: start}
+				return nil
+			}
+		}
+		return fmt.Errorf("policy: unexpected character %q at offset %d", c, start)
+	}
+	return nil
+}
+
+func policyIdentChar(c byte) bool {
+	return c == '_' || c == '.' || (c >= '0' && c <= '9') || unicode.IsLetter(rune(c))
+}
@@ -0,0 +1,216 @@
+package middlewarex_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/mdouchement/middlewarex"
+	"github.com/o1egl/paseto/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequirePolicy(t *testing.T) {
+	jt := paseto.JSONToken{Subject: "John Doe"}
+	jt.Set("role", "ops")
+	jt.Set("tenant", "acme")
+	jt.Set("level", 3)
+	jt.Set("admin", false)
+	jt.Set("zip", "02134")
+	jt.Set("org", map[string]any{"groups": []string{"dev", "sre"}})
+	token := policyToken(t, jt)
+
+	tests := []struct {
+		expr       string
+		expErrCode int // 0 for Success
+		expMessage string
+		info       string
+	}{
+		{
+			expr: `role in ["admin","ops"] && tenant == param("tenant")`,
+			info: "Valid role and tenant",
+		},
+		{
+			expr:       `role in ["admin","ops"] && tenant == query("tenant")`,
+			expErrCode: http.StatusForbidden,
+			expMessage: `policy denied: tenant == query("tenant")`,
+			info:       "Mismatching tenant",
+		},
+		{
+			expr:       `role == 'admin' && tenant == "acme"`,
+			expErrCode: http.StatusForbidden,
+			expMessage: `policy denied: role == 'admin'`,
+			info:       "Mismatching role",
+		},
+		{
+			expr: `admin || (level >= 3 && "sre" in org.groups)`,
+			info: "Nested claims and numbers",
+		},
+		{
+			expr:       `admin || level > 3`,
+			expErrCode: http.StatusForbidden,
+			expMessage: `policy denied: admin || level > 3`,
+			info:       "Failing or",
+		},
+		{
+			expr: `!admin && org.groups in ["sre"] && sub != null`,
+			info: "Negation, list claims and null",
+		},
+		{
+			expr: `header("X-Tenant") == tenant`,
+			info: "Header",
+		},
+		{
+			expr: `zip != "2134" && zip != "2134.0" && zip != "2.134e3" && zip == "02134"`,
+			info: "Numeric strings compared as strings",
+		},
+		{
+			expr:       `zip > "1"`,
+			expErrCode: http.StatusForbidden,
+			expMessage: `policy denied: zip > "1"`,
+			info:       "Numeric strings ordered as strings",
+		},
+		{
+			expr: `zip == 2134 && level == "3"`,
+			info: "Numeric strings compared to numbers",
+		},
+		{
+			expr:       `missing`,
+			expErrCode: http.StatusForbidden,
+			expMessage: `policy denied: missing`,
+			info:       "Missing claim",
+		},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/?tenant=other", nil)
+		req.Header.Set("X-Tenant", "acme")
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+		c.SetPathValues([]echo.PathValue{{Name: "tenant", Value: "acme"}})
+		c.Set(middlewarex.DefaultPASETOConfig.ContextKey, token)
+
+		h := middlewarex.RequirePolicy(test.expr)(func(c *echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		err := h(c)
+		if test.expErrCode == 0 {
+			assert.NoError(t, err, test.info)
+			continue
+		}
+
+		he, ok := err.(*echo.HTTPError)
+		if assert.True(t, ok, test.info) {
+			assert.Equal(t, test.expErrCode, he.Code, test.info)
+			assert.Equal(t, test.expMessage, he.Message, test.info)
+		}
+	}
+}
+
+func TestRequirePolicyNumericStrings(t *testing.T) {
+	jt := paseto.JSONToken{Subject: "John Doe"}
+	jt.Set("limit", 100)
+	jt.Set("org", map[string]any{"id": 42})
+	token := policyToken(t, jt)
+
+	tests := []struct {
+		value   string
+		allowed bool
+	}{
+		{value: "42", allowed: true},
+		{value: "42.0", allowed: true},
+		{value: "-1", allowed: false},
+		{value: "NaN"},
+		{value: "nan"},
+		{value: "Inf"},
+		{value: "+Inf"},
+		{value: "-Inf"},
+		{value: "4.2e1"},
+		{value: "0x2A"},
+		{value: "1" + strings.Repeat("0", 400)},
+	}
+
+	for _, test := range tests {
+		for _, expr := range []string{`org.id == query("v")`, `query("v") <= limit && query("v") >= 0`} {
+			req := httptest.NewRequest(http.MethodGet, "/?v="+url.QueryEscape(test.value), nil)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+			c.Set(middlewarex.DefaultPASETOConfig.ContextKey, token)
+
+			err := middlewarex.RequirePolicy(expr)(func(c *echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})(c)
+			if test.allowed {
+				assert.NoError(t, err, expr+" with "+test.value)
+			} else {
+				assert.Error(t, err, expr+" with "+test.value)
+			}
+		}
+	}
+}
+
+func TestRequirePolicyTokenSources(t *testing.T) {
+	jt := paseto.JSONToken{Subject: "John Doe"}
+	token := policyToken(t, jt)
+
+	h := middlewarex.RequirePolicy(`sub == "John Doe"`)(func(c *echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	// Without token
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	assert.Equal(t, middlewarex.ErrPolicyUnauthenticated, h(c))
+
+	// With a token stored by the net/http middleware
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(middlewarex.ContextWithToken(context.Background(), token))
+	c = echo.New().NewContext(req, httptest.NewRecorder())
+	assert.NoError(t, h(c))
+}
+
+func TestCompileClaimPolicy(t *testing.T) {
+	tests := []struct {
+		expr   string
+		expErr string
+	}{
+		{expr: `role == "admin" || (tenant == param("tenant") && !banned)`},
+		{expr: ``, expErr: "policy: unexpected end of expression"},
+		{expr: `role ==`, expErr: "policy: unexpected end of expression"},
+		{expr: `role == "admin`, expErr: "policy: unterminated string at offset 8"},
+		{expr: `(role == "admin"`, expErr: `policy: expecting ")" but got end of expression`},
+		{expr: `role in ["a" "b"]`, expErr: `policy: expecting "," but got "\"b\"" at offset 13`},
+		{expr: `role = "admin"`, expErr: `policy: unexpected character '=' at offset 5`},
+		{expr: `env("HOME") == ""`, expErr: `policy: unknown function "env" at offset 0`},
+		{expr: `param(tenant) == ""`, expErr: `policy: param() expects a string argument at offset 6`},
+		{expr: `role "admin"`, expErr: `policy: unexpected "\"admin\"" at offset 5`},
+	}
+
+	for _, test := range tests {
+		_, err := middlewarex.CompileClaimPolicy(test.expr)
+		if test.expErr == "" {
+			assert.NoError(t, err, test.expr)
+			continue
+		}
+		assert.EqualError(t, err, test.expErr, test.expr)
+	}
+
+	assert.PanicsWithValue(t, "policy: unexpected end of expression", func() {
+		middlewarex.RequirePolicy(`role ==`)
+	})
+}
+
+func policyToken(t *testing.T, jt paseto.JSONToken) middlewarex.Token {
+	key := []byte("400c48a557be10254d235cf8c506e6fe")
+	s, err := paseto.Encrypt(key, jt, "")
+	assert.NoError(t, err)
+
+	verifier, err := middlewarex.NewPASETOVerifier(middlewarex.PASETOConfig{SigningKey: key})
+	assert.NoError(t, err)
+
+	token, err := verifier.VerifyToken(s)
+	assert.NoError(t, err)
+	return token
+}